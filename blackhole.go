@@ -0,0 +1,94 @@
+package tfo
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBlackholeTimeout is the initial duration TFO is disabled for after a blackhole
+// is detected by a [Dialer] without a [Dialer.HealthCache].
+// It matches the default of the tcp_fastopen_blackhole_timeout_sec sysctl on Linux.
+const DefaultBlackholeTimeout = time.Hour
+
+// maxBlackholeTimeoutShift caps the exponential backoff of the blackhole timeout,
+// like the kernel does.
+const maxBlackholeTimeoutShift = 6
+
+// blackholeState is the process-wide TFO blackhole state.
+//
+// Each detection disables TFO for twice as long as the previous one,
+// until a SYN carrying data is answered again.
+type blackholeState struct {
+	until atomic.Int64 // UnixNano; TFO is disabled until then
+	times atomic.Uint32
+
+	mu sync.Mutex
+}
+
+func (s *blackholeState) active(now time.Time) bool {
+	return now.UnixNano() < s.until.Load()
+}
+
+func (s *blackholeState) detected(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shift := s.times.Load()
+	if shift > maxBlackholeTimeoutShift {
+		shift = maxBlackholeTimeoutShift
+	}
+	s.until.Store(now.Add(DefaultBlackholeTimeout << shift).UnixNano())
+	s.times.Add(1)
+}
+
+func (s *blackholeState) reset() {
+	if s.times.Load() == 0 {
+		return
+	}
+	s.mu.Lock()
+	s.times.Store(0)
+	s.until.Store(0)
+	s.mu.Unlock()
+}
+
+var runtimeDialTFOBlackhole blackholeState
+
+// tfoBlackholed reports whether TFO is disabled for the dialer by the process-wide blackhole state.
+func (d *Dialer) tfoBlackholed() bool {
+	return d.Fallback && d.HealthCache == nil && runtimeDialTFOBlackhole.active(time.Now())
+}
+
+// synDataCtx returns the context for waiting on the SYN-ACK of a SYN carrying data,
+// bounded by [Dialer.SYNDataTimeout]. If the timeout does not apply, ctx is returned as is.
+func (d *Dialer) synDataCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	if !d.Fallback || d.SYNDataTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d.SYNDataTimeout)
+}
+
+// synDataTimedOut returns whether a dial call failed because synCtx, returned by
+// [Dialer.synDataCtx], expired before ctx.
+func synDataTimedOut(ctx, synCtx context.Context) bool {
+	return synCtx != ctx && ctx.Err() == nil && synCtx.Err() == context.DeadlineExceeded
+}
+
+// markTFOBlackhole records that a SYN carrying data to the destination went unanswered,
+// in [Dialer.HealthCache], or process-wide if there is no health cache.
+func (d *Dialer) markTFOBlackhole(ap netip.AddrPort) {
+	if d.HealthCache != nil {
+		d.HealthCache.MarkFailed(ap)
+		return
+	}
+	runtimeDialTFOBlackhole.detected(time.Now())
+}
+
+// markTFOSYNDataAcked records that a SYN carrying data was answered,
+// which ends the backoff of the process-wide blackhole state.
+func (d *Dialer) markTFOSYNDataAcked() {
+	if d.HealthCache == nil {
+		runtimeDialTFOBlackhole.reset()
+	}
+}
@@ -0,0 +1,116 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenFullBacklog returns a listener whose accept queue holds one connection,
+// which is filled by the returned connection. While the queue is full, the kernel
+// drops incoming SYNs, as a blackhole would.
+func listenFullBacklog(t *testing.T) (*net.TCPListener, net.Conn) {
+	t.Helper()
+	fd, err := unix.Socket(unix.AF_INET6, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, unix.IPPROTO_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fd), "")
+	defer f.Close()
+	if err = unix.Bind(fd, &unix.SockaddrInet6{Addr: [16]byte{15: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = unix.Listen(fd, 0); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.FileListener(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	filler, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		filler.Close()
+	})
+	return ln.(*net.TCPListener), filler
+}
+
+// TestDialSYNDataBlackhole ensures that a SYN carrying data that goes unanswered
+// for [Dialer.SYNDataTimeout] is recorded as a blackhole, and that the address
+// is retried without TFO.
+func TestDialSYNDataBlackhole(t *testing.T) {
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+	}{
+		{"Default", runtimeFallbackAsIs},
+		{"LinuxSendto", runtimeFallbackSetDialLinuxSendto},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+			t.Cleanup(func() {
+				runtimeDialTFOBlackhole.reset()
+			})
+			testDialSYNDataBlackhole(t)
+		})
+	}
+}
+
+func testDialSYNDataBlackhole(t *testing.T) {
+	ln, _ := listenFullBacklog(t)
+
+	// Make room for the retry once the blackhole is detected.
+	var reasons []FallbackReason
+	ctx := WithDialTrace(context.Background(), &DialTrace{
+		Fallback: func(network, addr string, reason FallbackReason, err error) {
+			reasons = append(reasons, reason)
+			if reason != FallbackReasonBlackhole {
+				return
+			}
+			if c, err := ln.Accept(); err == nil {
+				c.Close()
+			}
+		},
+	})
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Send the data in the SYN without waiting for a cookie.
+	d := Dialer{Fallback: true, NoCookie: true, SYNDataTimeout: 200 * time.Millisecond}
+	c, info, err := d.DialContextInfo(ctx, "tcp", ln.Addr().String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if info.Method != DialMethodFallback || info.FallbackReason != FallbackReasonBlackhole {
+		t.Errorf("info = %+v, want Method %v and FallbackReason %v", info, DialMethodFallback, FallbackReasonBlackhole)
+	}
+	if info.FallbackError == nil || info.FallbackError.Stage != TFOStageConnect {
+		t.Errorf("info.FallbackError = %v, want error at stage %v", info.FallbackError, TFOStageConnect)
+	}
+	if len(reasons) != 1 || reasons[0] != FallbackReasonBlackhole {
+		t.Errorf("fallback reasons = %v, want [%v]", reasons, FallbackReasonBlackhole)
+	}
+	if !d.tfoBlackholed() {
+		t.Error("blackhole was not recorded")
+	}
+
+	sc, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	c.(*net.TCPConn).CloseWrite()
+	readUntilEOF(sc, hello, t)
+}
@@ -0,0 +1,112 @@
+package tfo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBlackholeStateBackoff(t *testing.T) {
+	var s blackholeState
+	now := time.Now()
+
+	if s.active(now) {
+		t.Fatal("zero blackholeState is active")
+	}
+
+	for i := 0; i <= maxBlackholeTimeoutShift+1; i++ {
+		s.detected(now)
+		shift := i
+		if shift > maxBlackholeTimeoutShift {
+			shift = maxBlackholeTimeoutShift
+		}
+		timeout := DefaultBlackholeTimeout << shift
+		if !s.active(now.Add(timeout - time.Second)) {
+			t.Errorf("detection %d: not active before %v", i+1, timeout)
+		}
+		if s.active(now.Add(timeout)) {
+			t.Errorf("detection %d: still active after %v", i+1, timeout)
+		}
+	}
+
+	s.reset()
+	if s.active(now) {
+		t.Error("active after reset")
+	}
+	s.detected(now)
+	if s.active(now.Add(DefaultBlackholeTimeout)) {
+		t.Error("backoff was not reset")
+	}
+}
+
+func runtimeFallbackSetDialBlackhole(t *testing.T) {
+	runtimeDialTFOBlackhole.detected(time.Now())
+	t.Cleanup(func() {
+		runtimeDialTFOBlackhole.reset()
+	})
+}
+
+// TestDialSYNDataTimeout ensures that [Dialer.SYNDataTimeout] does not affect
+// dial calls to a responsive server, and that a detected blackhole makes
+// fallback dialers skip TFO.
+func TestDialSYNDataTimeout(t *testing.T) {
+	if comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	s, err := newDiscardTCPServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(t)
+	defer s.Close()
+
+	address := s.Addr().String()
+
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+	}{
+		{"Default", runtimeFallbackAsIs},
+		{"LinuxSendto", runtimeFallbackSetDialLinuxSendto},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+
+			d := Dialer{Fallback: true, SYNDataTimeout: 5 * time.Second}
+			for i := 0; i < 2; i++ {
+				conn, info, err := d.DialContextInfo(context.Background(), "tcp", address, hello)
+				if err != nil {
+					t.Fatal(err)
+				}
+				conn.Close()
+				t.Logf("info: %+v", info)
+				if info.Method == DialMethodFallback && d.TFO() {
+					t.Errorf("info.Method = %v with SYNDataTimeout", info.Method)
+				}
+			}
+		})
+	}
+
+	t.Run("Blackholed", func(t *testing.T) {
+		runtimeFallbackSetDialBlackhole(t)
+
+		d := Dialer{Fallback: true}
+		if d.TFO() {
+			t.Error("d.TFO() = true while blackholed")
+		}
+		conn, info, err := d.DialContextInfo(context.Background(), "tcp", address, hello)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		if info.Method != DialMethodFallback {
+			t.Errorf("info.Method = %v while blackholed, want %v", info.Method, DialMethodFallback)
+		}
+
+		d.HealthCache = new(HealthCache)
+		if !d.TFO() {
+			t.Error("d.TFO() = false with HealthCache while blackholed")
+		}
+	})
+}
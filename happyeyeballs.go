@@ -0,0 +1,54 @@
+package tfo
+
+import "time"
+
+// DefaultConnectionAttemptDelay is the default [HappyEyeballs.ConnectionAttemptDelay],
+// as recommended by RFC 8305.
+const DefaultConnectionAttemptDelay = 250 * time.Millisecond
+
+// minConnectionAttemptDelay is the lower bound RFC 8305 puts on the connection attempt delay.
+const minConnectionAttemptDelay = 10 * time.Millisecond
+
+// HappyEyeballs configures dialing with Happy Eyeballs version 2, as specified in RFC 8305.
+//
+// The resolved addresses are sorted by the destination address selection rules of RFC 6724,
+// and the address families are interleaved. Connection attempts are started one at a time,
+// each after the previous one failed or [HappyEyeballs.ConnectionAttemptDelay] elapsed.
+// Every attempt carries the data in SYN, and the first established connection wins.
+type HappyEyeballs struct {
+	// ConnectionAttemptDelay is the time to wait for a connection attempt
+	// before starting the next one.
+	// If zero, [DefaultConnectionAttemptDelay] is used. Values below 10ms are raised to 10ms.
+	ConnectionAttemptDelay time.Duration
+
+	// ResolutionDelay, if positive, makes the dialer look up AAAA and A records in parallel.
+	// Connection attempts start as soon as AAAA records arrive, or ResolutionDelay
+	// after A records arrive if AAAA records are still pending.
+	// Records that arrive later are added to the addresses yet to be tried.
+	// RFC 8305 recommends 50ms.
+	//
+	// If zero, connection attempts start after all lookups complete.
+	ResolutionDelay time.Duration
+
+	// FirstAddressFamilyCount is the number of addresses of the preferred address family
+	// to try before trying the other address family. If zero, 1 is used.
+	FirstAddressFamilyCount int
+}
+
+func (he *HappyEyeballs) connectionAttemptDelay() time.Duration {
+	switch {
+	case he.ConnectionAttemptDelay == 0:
+		return DefaultConnectionAttemptDelay
+	case he.ConnectionAttemptDelay < minConnectionAttemptDelay:
+		return minConnectionAttemptDelay
+	default:
+		return he.ConnectionAttemptDelay
+	}
+}
+
+func (he *HappyEyeballs) firstAddressFamilyCount() int {
+	if he.FirstAddressFamilyCount < 1 {
+		return 1
+	}
+	return he.FirstAddressFamilyCount
+}
@@ -0,0 +1,499 @@
+//go:build darwin || freebsd || linux || windows
+
+package tfo
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// dialHappyEyeballs resolves host and dials the addresses with Happy Eyeballs version 2.
+// If ras is not nil, they are dialed instead.
+func (d *Dialer) dialHappyEyeballs(ctx context.Context, network string, laddr *net.TCPAddr, host string, port int, ras []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	he := d.HappyEyeballs
+
+	type lookupResult struct {
+		addrs []*net.TCPAddr
+		err   error
+		ipv6  bool
+	}
+
+	lookupCtx, lookupCancel := context.WithCancel(ctx)
+	defer lookupCancel()
+
+	var (
+		lookups        chan lookupResult
+		lookupsPending int
+		lookupErr      error
+		pending        []*net.TCPAddr
+		started        bool // whether a connection attempt has been started
+		sorter         rfc6724Sorter
+	)
+
+	addAddrs := func(addrs []*net.TCPAddr) {
+		var added []*net.TCPAddr
+		for _, a := range addrs {
+			if networkMatchesAddr(network, a.IP) {
+				added = append(added, a)
+			}
+		}
+		pending = sorter.merge(pending, added, started, he.firstAddressFamilyCount())
+	}
+
+	addLookupResult := func(res lookupResult) {
+		lookupsPending--
+		if res.err != nil {
+			if lookupErr == nil {
+				lookupErr = res.err
+			}
+			return
+		}
+		addAddrs(res.addrs)
+	}
+
+	if ras != nil {
+		addAddrs(ras)
+	} else if _, err := netip.ParseAddr(host); err != nil && he.ResolutionDelay > 0 && network == "tcp" {
+		// Look up AAAA and A records in parallel.
+		lookups = make(chan lookupResult, 2)
+		lookupsPending = 2
+		for _, ipv6 := range [2]bool{true, false} {
+			family := "ip4"
+			if ipv6 {
+				family = "ip6"
+			}
+			go func(family string, ipv6 bool) {
+				addrs, err := d.lookupTCPAddrs(lookupCtx, network, family, laddr, host, port)
+				lookups <- lookupResult{addrs: addrs, err: err, ipv6: ipv6}
+			}(family, ipv6)
+		}
+
+		// Wait for the first answer, and give AAAA records a head start over A records.
+		var resolutionDelay <-chan time.Time
+		for lookupsPending > 0 && len(pending) == 0 || resolutionDelay != nil {
+			select {
+			case res := <-lookups:
+				addLookupResult(res)
+				switch {
+				case res.ipv6 && len(res.addrs) > 0 || lookupsPending == 0:
+					resolutionDelay = nil
+				case len(pending) > 0 && resolutionDelay == nil:
+					timer := time.NewTimer(he.ResolutionDelay)
+					defer timer.Stop()
+					resolutionDelay = timer.C
+				}
+			case <-resolutionDelay:
+				resolutionDelay = nil
+			case <-ctx.Done():
+				return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: ctx.Err()}
+			}
+		}
+	} else {
+		addrs, err := d.lookupTCPAddrs(ctx, network, "ip", laddr, host, port)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		addAddrs(addrs)
+	}
+
+	if len(pending) == 0 && lookupsPending == 0 {
+		if lookupErr != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: lookupErr}
+		}
+		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: errMissingAddress}
+	}
+
+	type dialResult struct {
+		*net.TCPConn
+		error
+		info DialInfo
+		ra   *net.TCPAddr
+	}
+
+	// The attempts may outlive this call, so they must not share bufs with the caller.
+	bufs = append([][]byte(nil), bufs...)
+
+	returned := make(chan struct{})
+	defer close(returned)
+	results := make(chan dialResult) // unbuffered
+
+	attemptsCtx, attemptsCancel := context.WithCancel(ctx)
+	defer attemptsCancel()
+
+	var (
+		active       int
+		firstErr     error // The error from the first address is most relevant.
+		attemptTimer *time.Timer
+		attemptDelay <-chan time.Time
+	)
+	defer func() {
+		if attemptTimer != nil {
+			attemptTimer.Stop()
+		}
+	}()
+
+	startAttempt := func() {
+		ra := pending[0]
+		pending = pending[1:]
+		started = true
+
+		dialCtx, cancel := attemptsCtx, context.CancelFunc(func() {})
+		partialDeadline, err := d.attemptDeadline(ctx, time.Now(), len(pending)+1)
+		if deadline, hasDeadline := ctx.Deadline(); err == nil && !partialDeadline.IsZero() && (!hasDeadline || partialDeadline.Before(deadline)) {
+			dialCtx, cancel = context.WithDeadline(attemptsCtx, partialDeadline)
+		}
+
+		active++
+		go func() {
+			defer cancel()
+			var info DialInfo
+			c, err := d.dialAddr(dialCtx, network, laddr, ra, bufs, opts, &info)
+			select {
+			case results <- dialResult{TCPConn: c, error: err, info: info, ra: ra}:
+			case <-returned:
+				if c != nil {
+					c.Close()
+				}
+			}
+		}()
+
+		if attemptTimer != nil {
+			attemptTimer.Stop()
+		}
+		attemptTimer = time.NewTimer(he.connectionAttemptDelay())
+		attemptDelay = attemptTimer.C
+	}
+
+	for {
+		if active == 0 && len(pending) > 0 {
+			startAttempt()
+		}
+		if active == 0 && lookupsPending == 0 {
+			if firstErr == nil {
+				firstErr = &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: errMissingAddress}
+			}
+			return nil, firstErr
+		}
+
+		select {
+		case <-attemptDelay:
+			attemptDelay = nil
+			if len(pending) > 0 {
+				startAttempt()
+			}
+
+		case res := <-results:
+			active--
+			if res.error == nil {
+				*info = res.info
+				return res.TCPConn, nil
+			}
+			if firstErr == nil {
+				firstErr = &net.OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: res.ra, Err: res.error}
+			}
+			// Start the next attempt right away.
+			if len(pending) > 0 {
+				startAttempt()
+			}
+
+		case res := <-lookups:
+			addLookupResult(res)
+		}
+	}
+}
+
+// interleaveAddrFamilies reorders addrs so that, after the first firstCount addresses
+// of the family of the first address, the address families alternate.
+// The relative order of addresses of the same family is kept.
+func interleaveAddrFamilies(addrs []*net.TCPAddr, firstCount int) []*net.TCPAddr {
+	if len(addrs) < 2 {
+		return addrs
+	}
+	first, other := partition(addrs, func(a *net.TCPAddr) bool {
+		return matchAddrFamily(a.IP, addrs[0].IP)
+	})
+	interleaved := make([]*net.TCPAddr, 0, len(addrs))
+	if firstCount > len(first) {
+		firstCount = len(first)
+	}
+	interleaved = append(interleaved, first[:firstCount]...)
+	first = first[firstCount:]
+	for len(first) > 0 || len(other) > 0 {
+		if len(other) > 0 {
+			interleaved = append(interleaved, other[0])
+			other = other[1:]
+		}
+		if len(first) > 0 {
+			interleaved = append(interleaved, first[0])
+			first = first[1:]
+		}
+	}
+	return interleaved
+}
+
+// rfc6724Policy is an entry of the default policy table of RFC 6724, section 2.1.
+type rfc6724Policy struct {
+	prefix     netip.Prefix
+	precedence uint8
+	label      uint8
+}
+
+// rfc6724PolicyTable is the default policy table, ordered by decreasing prefix length
+// so that the first match is the longest.
+var rfc6724PolicyTable = []rfc6724Policy{
+	{netip.MustParsePrefix("::1/128"), 50, 0},
+	{netip.MustParsePrefix("::ffff:0:0/96"), 35, 4},
+	{netip.MustParsePrefix("::/96"), 1, 3},
+	{netip.MustParsePrefix("2001::/32"), 5, 5},
+	{netip.MustParsePrefix("2002::/16"), 30, 2},
+	{netip.MustParsePrefix("3ffe::/16"), 1, 12},
+	{netip.MustParsePrefix("fec0::/10"), 1, 11},
+	{netip.MustParsePrefix("fc00::/7"), 3, 13},
+	{netip.MustParsePrefix("::/0"), 40, 1},
+}
+
+func rfc6724ClassifyAddr(a netip.Addr) rfc6724Policy {
+	a = netip.AddrFrom16(a.As16()) // IPv4 addresses are classified as IPv4-mapped.
+	for _, p := range rfc6724PolicyTable {
+		if p.prefix.Contains(a) {
+			return p
+		}
+	}
+	return rfc6724Policy{}
+}
+
+// Address scopes from RFC 4291 and RFC 6724, section 3.
+const (
+	rfc6724ScopeLinkLocal = 0x2
+	rfc6724ScopeSiteLocal = 0x5
+	rfc6724ScopeGlobal    = 0xe
+)
+
+func rfc6724Scope(a netip.Addr) uint8 {
+	a = a.Unmap()
+	switch {
+	case a.Is6() && a.IsMulticast():
+		return a.As16()[1] & 0xf
+	case a.IsLoopback(), a.IsLinkLocalUnicast():
+		return rfc6724ScopeLinkLocal
+	case a.Is6() && a.As16()[0] == 0xfe && a.As16()[1]&0xc0 == 0xc0:
+		return rfc6724ScopeSiteLocal
+	default:
+		return rfc6724ScopeGlobal
+	}
+}
+
+// rfc6724Source returns the source address the system would use to reach dst,
+// or the zero [netip.Addr] if dst is unreachable. No packets are sent.
+func rfc6724Source(dst netip.Addr, zone string) netip.Addr {
+	c, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: dst.AsSlice(), Port: 9, Zone: zone})
+	if err != nil {
+		return netip.Addr{}
+	}
+	defer c.Close()
+	return c.LocalAddr().(*net.UDPAddr).AddrPort().Addr().Unmap()
+}
+
+type rfc6724Dest struct {
+	addr *net.TCPAddr
+	dst  netip.Addr
+	src  netip.Addr
+}
+
+// rfc6724Sorter sorts addresses by the destination address selection rules of RFC 6724,
+// section 6, that do not need information beyond the source addresses.
+// It remembers the source address of each destination, so that the system
+// is only asked once per destination, however many times addresses are sorted.
+type rfc6724Sorter struct {
+	sources map[netip.Addr]netip.Addr // keyed by destination, with zone
+}
+
+func (s *rfc6724Sorter) dest(a *net.TCPAddr) rfc6724Dest {
+	dst, _ := netip.AddrFromSlice(a.IP)
+	dst = dst.Unmap()
+	key := dst.WithZone(a.Zone)
+	src, ok := s.sources[key]
+	if !ok {
+		src = rfc6724Source(dst, a.Zone)
+		if s.sources == nil {
+			s.sources = make(map[netip.Addr]netip.Addr)
+		}
+		s.sources[key] = src
+	}
+	return rfc6724Dest{addr: a, dst: dst, src: src}
+}
+
+// less reports whether destination a is preferred over destination b.
+func (s *rfc6724Sorter) less(a, b *net.TCPAddr) bool {
+	da, db := s.dest(a), s.dest(b)
+	return rfc6724Less(&da, &db)
+}
+
+// sort sorts addrs. The sort is stable, so that addresses that are equally preferred
+// keep their order.
+func (s *rfc6724Sorter) sort(addrs []*net.TCPAddr) {
+	if len(addrs) < 2 {
+		return
+	}
+	dests := make([]rfc6724Dest, len(addrs))
+	for i, a := range addrs {
+		dests[i] = s.dest(a)
+	}
+	sort.SliceStable(dests, func(i, j int) bool {
+		return rfc6724Less(&dests[i], &dests[j])
+	})
+	for i := range dests {
+		addrs[i] = dests[i].addr
+	}
+}
+
+// merge sorts added and merges it into pending, the addresses yet to be tried,
+// as returned by a previous call. The addresses of each family are kept sorted,
+// and the address families are interleaved again. Before the first connection attempt
+// is started, the most preferred address goes first, followed by firstCount-1 addresses
+// of its family. Afterwards, the address that was to be tried next stays first.
+func (s *rfc6724Sorter) merge(pending, added []*net.TCPAddr, started bool, firstCount int) []*net.TCPAddr {
+	if len(added) == 0 {
+		return pending
+	}
+	s.sort(added)
+	pending6, pending4 := splitAddrFamilies(pending)
+	added6, added4 := splitAddrFamilies(added)
+	addrs6, addrs4 := s.mergeSorted(pending6, added6), s.mergeSorted(pending4, added4)
+
+	var ipv6First bool
+	switch {
+	case started && len(pending) > 0:
+		ipv6First = pending[0].IP.To4() == nil
+	case len(addrs4) == 0:
+		ipv6First = true
+	case len(addrs6) == 0:
+		ipv6First = false
+	default:
+		ipv6First = !s.less(addrs4[0], addrs6[0])
+	}
+	if started {
+		firstCount = 1
+	}
+
+	merged := make([]*net.TCPAddr, 0, len(addrs6)+len(addrs4))
+	if ipv6First {
+		merged = append(append(merged, addrs6...), addrs4...)
+	} else {
+		merged = append(append(merged, addrs4...), addrs6...)
+	}
+	return interleaveAddrFamilies(merged, firstCount)
+}
+
+// mergeSorted merges the sorted lists a and b. Addresses of a go first
+// when equally preferred.
+func (s *rfc6724Sorter) mergeSorted(a, b []*net.TCPAddr) []*net.TCPAddr {
+	if len(b) == 0 {
+		return a
+	}
+	merged := make([]*net.TCPAddr, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if s.less(b[0], a[0]) {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// splitAddrFamilies splits addrs into IPv6 and IPv4 addresses, keeping their order.
+func splitAddrFamilies(addrs []*net.TCPAddr) (ipv6, ipv4 []*net.TCPAddr) {
+	for _, a := range addrs {
+		if a.IP.To4() == nil {
+			ipv6 = append(ipv6, a)
+		} else {
+			ipv4 = append(ipv4, a)
+		}
+	}
+	return ipv6, ipv4
+}
+
+// rfc6724Less reports whether destination a is preferred over destination b.
+func rfc6724Less(a, b *rfc6724Dest) bool {
+	// Rule 1: Avoid unusable destinations.
+	if a.src.IsValid() != b.src.IsValid() {
+		return a.src.IsValid()
+	}
+	if !a.src.IsValid() {
+		return false
+	}
+
+	aScope, bScope := rfc6724Scope(a.dst), rfc6724Scope(b.dst)
+
+	// Rule 2: Prefer matching scope.
+	aMatch, bMatch := aScope == rfc6724Scope(a.src), bScope == rfc6724Scope(b.src)
+	if aMatch != bMatch {
+		return aMatch
+	}
+
+	// Rules 3 and 4 need information about deprecated and home addresses.
+
+	aPolicy, bPolicy := rfc6724ClassifyAddr(a.dst), rfc6724ClassifyAddr(b.dst)
+
+	// Rule 5: Prefer matching label.
+	aMatch = aPolicy.label == rfc6724ClassifyAddr(a.src).label
+	bMatch = bPolicy.label == rfc6724ClassifyAddr(b.src).label
+	if aMatch != bMatch {
+		return aMatch
+	}
+
+	// Rule 6: Prefer higher precedence.
+	if aPolicy.precedence != bPolicy.precedence {
+		return aPolicy.precedence > bPolicy.precedence
+	}
+
+	// Rule 7 needs information about encapsulating transition mechanisms.
+
+	// Rule 8: Prefer smaller scope.
+	if aScope != bScope {
+		return aScope < bScope
+	}
+
+	// Rule 9: Use longest matching prefix. Like most implementations,
+	// only apply it to IPv6 addresses, up to the length of a typical subnet prefix.
+	if a.dst.Is6() && b.dst.Is6() {
+		aLen, bLen := commonPrefixLen(a.dst, a.src), commonPrefixLen(b.dst, b.src)
+		if aLen != bLen {
+			return aLen > bLen
+		}
+	}
+
+	// Rule 10: Otherwise, leave the order unchanged.
+	return false
+}
+
+// commonPrefixLen returns the length of the common prefix of a and b, up to 64 bits.
+func commonPrefixLen(a, b netip.Addr) (n int) {
+	if a.Is4() != b.Is4() {
+		return 0
+	}
+	as, bs := a.AsSlice(), b.AsSlice()
+	if len(as) > 8 {
+		as, bs = as[:8], bs[:8]
+	}
+	for i := range as {
+		x := as[i] ^ bs[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+		break
+	}
+	return n
+}
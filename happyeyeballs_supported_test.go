@@ -0,0 +1,309 @@
+//go:build darwin || freebsd || linux || windows
+
+package tfo
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func parseTCPAddrs(t *testing.T, ss ...string) []*net.TCPAddr {
+	addrs := make([]*net.TCPAddr, len(ss))
+	for i, s := range ss {
+		addrs[i] = net.TCPAddrFromAddrPort(netip.AddrPortFrom(netip.MustParseAddr(s), 443))
+	}
+	return addrs
+}
+
+func tcpAddrsString(addrs []*net.TCPAddr) string {
+	var s string
+	for i, a := range addrs {
+		if i > 0 {
+			s += " "
+		}
+		s += a.IP.String()
+	}
+	return s
+}
+
+func TestInterleaveAddrFamilies(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		addrs      []string
+		firstCount int
+		want       string
+	}{
+		{
+			name:       "Empty",
+			firstCount: 1,
+		},
+		{
+			name:       "SingleFamily",
+			addrs:      []string{"2001:db8::1", "2001:db8::2"},
+			firstCount: 1,
+			want:       "2001:db8::1 2001:db8::2",
+		},
+		{
+			name:       "IPv6First",
+			addrs:      []string{"2001:db8::1", "2001:db8::2", "2001:db8::3", "192.0.2.1", "192.0.2.2"},
+			firstCount: 1,
+			want:       "2001:db8::1 192.0.2.1 2001:db8::2 192.0.2.2 2001:db8::3",
+		},
+		{
+			name:       "IPv4First",
+			addrs:      []string{"192.0.2.1", "192.0.2.2", "2001:db8::1"},
+			firstCount: 1,
+			want:       "192.0.2.1 2001:db8::1 192.0.2.2",
+		},
+		{
+			name:       "FirstAddressFamilyCount",
+			addrs:      []string{"2001:db8::1", "2001:db8::2", "2001:db8::3", "192.0.2.1", "192.0.2.2"},
+			firstCount: 2,
+			want:       "2001:db8::1 2001:db8::2 192.0.2.1 2001:db8::3 192.0.2.2",
+		},
+		{
+			name:       "FirstAddressFamilyCountAboveLen",
+			addrs:      []string{"2001:db8::1", "192.0.2.1", "192.0.2.2"},
+			firstCount: 3,
+			want:       "2001:db8::1 192.0.2.1 192.0.2.2",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got := tcpAddrsString(interleaveAddrFamilies(parseTCPAddrs(t, c.addrs...), c.firstCount))
+			if got != c.want {
+				t.Errorf("interleaveAddrFamilies() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRFC6724Less(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		a, b     rfc6724Dest
+		wantLess bool
+	}{
+		{
+			name:     "Rule1Unusable",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("2001:db8::1")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("198.51.100.1"), src: netip.MustParseAddr("198.51.100.2")},
+			wantLess: false,
+		},
+		{
+			name:     "Rule2MatchingScope",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("2001:db8::1"), src: netip.MustParseAddr("2001:db8::2")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("198.51.100.1"), src: netip.MustParseAddr("169.254.1.1")},
+			wantLess: true,
+		},
+		{
+			name:     "Rule5MatchingLabel",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("2002:c633:6401::1"), src: netip.MustParseAddr("2002:c633:6401::2")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("2001:db8::1"), src: netip.MustParseAddr("2002:c633:6401::2")},
+			wantLess: true,
+		},
+		{
+			name:     "Rule6HigherPrecedence",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("2001:db8::1"), src: netip.MustParseAddr("2001:db8::2")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("198.51.100.1"), src: netip.MustParseAddr("198.51.100.2")},
+			wantLess: true,
+		},
+		{
+			name:     "Rule6Loopback",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("::1"), src: netip.MustParseAddr("::1")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("127.0.0.1"), src: netip.MustParseAddr("127.0.0.1")},
+			wantLess: true,
+		},
+		{
+			name:     "Rule8SmallerScope",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("2001:db8::1"), src: netip.MustParseAddr("2001:db8::2")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("fe80::1"), src: netip.MustParseAddr("fe80::2")},
+			wantLess: false,
+		},
+		{
+			name:     "Rule9LongestMatchingPrefix",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("2001:db8:1::1"), src: netip.MustParseAddr("2001:db8:1::2")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("2001:db8:2::1"), src: netip.MustParseAddr("2001:db8:1::2")},
+			wantLess: true,
+		},
+		{
+			name:     "Rule9IPv4",
+			a:        rfc6724Dest{dst: netip.MustParseAddr("198.51.100.1"), src: netip.MustParseAddr("198.51.100.2")},
+			b:        rfc6724Dest{dst: netip.MustParseAddr("203.0.113.1"), src: netip.MustParseAddr("198.51.100.2")},
+			wantLess: false,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := rfc6724Less(&c.a, &c.b); got != c.wantLess {
+				t.Errorf("rfc6724Less(%v, %v) = %v, want %v", c.a.dst, c.b.dst, got, c.wantLess)
+			}
+		})
+	}
+}
+
+func TestRFC6724SorterMerge(t *testing.T) {
+	// Cached source addresses, so that the system is not asked.
+	sources := map[netip.Addr]netip.Addr{
+		netip.MustParseAddr("2600::1"):   netip.MustParseAddr("2600::ff"),
+		netip.MustParseAddr("2600::2"):   netip.MustParseAddr("2600::ff"),
+		netip.MustParseAddr("2600::3"):   netip.MustParseAddr("2600::ff"),
+		netip.MustParseAddr("2600:1::1"): {}, // unreachable
+		netip.MustParseAddr("192.0.2.1"): netip.MustParseAddr("192.0.2.255"),
+		netip.MustParseAddr("192.0.2.2"): netip.MustParseAddr("192.0.2.255"),
+	}
+
+	for _, c := range []struct {
+		name       string
+		pending    []string
+		added      []string
+		started    bool
+		firstCount int
+		want       string
+	}{
+		{
+			name:       "Initial",
+			added:      []string{"192.0.2.1", "2600::1", "192.0.2.2", "2600::2"},
+			firstCount: 1,
+			want:       "2600::1 192.0.2.1 2600::2 192.0.2.2",
+		},
+		{
+			name:       "Unreachable",
+			added:      []string{"2600:1::1", "192.0.2.1"},
+			firstCount: 1,
+			want:       "192.0.2.1 2600:1::1",
+		},
+		{
+			name:       "LateBeforeStart",
+			pending:    []string{"2600::1", "2600::2"},
+			added:      []string{"192.0.2.1", "192.0.2.2"},
+			firstCount: 1,
+			want:       "2600::1 192.0.2.1 2600::2 192.0.2.2",
+		},
+		{
+			name:       "LateSameFamily",
+			pending:    []string{"2600::1", "192.0.2.1", "2600:1::1"},
+			added:      []string{"2600::2"},
+			firstCount: 1,
+			want:       "2600::1 192.0.2.1 2600::2 2600:1::1",
+		},
+		{
+			name:       "LateAfterStart",
+			pending:    []string{"2600::2", "2600::3"},
+			added:      []string{"192.0.2.1", "192.0.2.2"},
+			started:    true,
+			firstCount: 2,
+			want:       "2600::2 192.0.2.1 2600::3 192.0.2.2",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			s := rfc6724Sorter{sources: make(map[netip.Addr]netip.Addr, len(sources))}
+			for k, v := range sources {
+				s.sources[k] = v
+			}
+			got := tcpAddrsString(s.merge(parseTCPAddrs(t, c.pending...), parseTCPAddrs(t, c.added...), c.started, c.firstCount))
+			if got != c.want {
+				t.Errorf("merge() = %q, want %q", got, c.want)
+			}
+			if len(s.sources) != len(sources) {
+				t.Errorf("merge() looked up %d uncached source addresses", len(s.sources)-len(sources))
+			}
+		})
+	}
+}
+
+func TestCommonPrefixLen(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"2001:db8::1", "2001:db8::1", 64},
+		{"2001:db8::1", "2001:db9::1", 31},
+		{"2001:db8::1", "::1", 2},
+		{"192.0.2.1", "192.0.2.129", 24},
+		{"192.0.2.1", "2001:db8::1", 0},
+	} {
+		if got := commonPrefixLen(netip.MustParseAddr(c.a), netip.MustParseAddr(c.b)); got != c.want {
+			t.Errorf("commonPrefixLen(%s, %s) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func localhostHasBothFamilies() bool {
+	addrs, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", "localhost")
+	if err != nil {
+		return false
+	}
+	var has4, has6 bool
+	for _, a := range addrs {
+		if a.Unmap().Is4() {
+			has4 = true
+		} else {
+			has6 = true
+		}
+	}
+	return has4 && has6
+}
+
+// TestDialHappyEyeballs ensures that dial calls with Happy Eyeballs move on to the next address
+// when the connection attempt to the first one fails, and still deliver all of the data.
+func TestDialHappyEyeballs(t *testing.T) {
+	hosts := []string{"127.0.0.1"}
+	if localhostHasBothFamilies() {
+		hosts = append(hosts, "localhost")
+	} else {
+		t.Log("localhost does not resolve to both IPv4 and IPv6 addresses, only dialing 127.0.0.1")
+	}
+
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+			for _, host := range hosts {
+				for _, he := range []HappyEyeballs{
+					{},
+					{ConnectionAttemptDelay: time.Millisecond, ResolutionDelay: 50 * time.Millisecond},
+				} {
+					d := c.dialer
+					d.HappyEyeballs = &he
+					testDialHappyEyeballs(t, d, host)
+				}
+			}
+		})
+	}
+}
+
+func testDialHappyEyeballs(t *testing.T, d Dialer, host string) {
+	// Only listen on IPv4, so that attempts to the IPv6 loopback address are refused.
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, helloWorldSentence, t)
+	}()
+
+	address := net.JoinHostPort(host, strconv.Itoa(ln.Addr().(*net.TCPAddr).Port))
+	c, err := d.Dial("tcp", address, helloWorldSentence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if ra := c.RemoteAddr().(*net.TCPAddr); ra.IP.To4() == nil {
+		t.Errorf("c.RemoteAddr() = %v, want an IPv4 address", ra)
+	}
+	c.(*net.TCPConn).CloseWrite()
+	<-ctrlCh
+}
@@ -0,0 +1,132 @@
+package tfo
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// DefaultHealthTTL is the duration a destination is dialed without TFO after a failure,
+// used by [HealthCache] when [HealthCache.TTL] is not set.
+const DefaultHealthTTL = 10 * time.Minute
+
+// HealthCache records TFO failures per destination, so that a broken path only
+// disables TFO for the destinations behind it, instead of for the whole process.
+//
+// Destinations are keyed by IP prefix and port. After a failure, the destination
+// is dialed without TFO until its entry expires, at which point TFO is attempted again.
+//
+// A HealthCache is safe for concurrent use, and may be shared between [Dialer] instances.
+// The zero value is ready to use. The exported fields must not be modified after first use.
+type HealthCache struct {
+	// TTL is the duration a destination is dialed without TFO after a failure.
+	// If zero, [DefaultHealthTTL] is used.
+	TTL time.Duration
+
+	// IPv4PrefixLen is the length of the prefix IPv4 destinations are grouped by.
+	// If zero, each address is tracked on its own.
+	IPv4PrefixLen int
+
+	// IPv6PrefixLen is the length of the prefix IPv6 destinations are grouped by.
+	// If zero, each address is tracked on its own.
+	IPv6PrefixLen int
+
+	mu        sync.Mutex
+	entries   map[healthKey]time.Time // expiry time of each failure entry
+	nextSweep time.Time
+}
+
+type healthKey struct {
+	prefix netip.Prefix
+	port   uint16
+}
+
+func (h *HealthCache) ttl() time.Duration {
+	if h.TTL > 0 {
+		return h.TTL
+	}
+	return DefaultHealthTTL
+}
+
+// key returns the cache key for the destination.
+func (h *HealthCache) key(ap netip.AddrPort) healthKey {
+	addr := ap.Addr().Unmap().WithZone("")
+	bits := h.IPv6PrefixLen
+	if addr.Is4() {
+		bits = h.IPv4PrefixLen
+	}
+	if bits <= 0 || bits > addr.BitLen() {
+		bits = addr.BitLen()
+	}
+	prefix, _ := addr.Prefix(bits)
+	return healthKey{prefix: prefix, port: ap.Port()}
+}
+
+// Healthy reports whether TFO should be attempted to the destination,
+// i.e. there is no unexpired failure recorded for it.
+func (h *HealthCache) Healthy(ap netip.AddrPort) bool {
+	k := h.key(ap)
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	expiry, ok := h.entries[k]
+	if !ok {
+		return true
+	}
+	if now.Before(expiry) {
+		return false
+	}
+	delete(h.entries, k)
+	return true
+}
+
+// MarkFailed records a TFO failure for the destination.
+func (h *HealthCache) MarkFailed(ap netip.AddrPort) {
+	k := h.key(ap)
+	now := time.Now()
+	ttl := h.ttl()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.entries == nil {
+		h.entries = make(map[healthKey]time.Time)
+	}
+	if !now.Before(h.nextSweep) {
+		for k, expiry := range h.entries {
+			if !now.Before(expiry) {
+				delete(h.entries, k)
+			}
+		}
+		h.nextSweep = now.Add(ttl)
+	}
+	h.entries[k] = now.Add(ttl)
+}
+
+// MarkHealthy removes any failure recorded for the destination.
+func (h *HealthCache) MarkHealthy(ap netip.AddrPort) {
+	k := h.key(ap)
+	h.mu.Lock()
+	delete(h.entries, k)
+	h.mu.Unlock()
+}
+
+// Observe records a failure for the destination if info shows that the peer or a middlebox
+// did not handle the data in SYN, e.g. it was not acknowledged, or the SYN had to be
+// retransmitted without data.
+func (h *HealthCache) Observe(ap netip.AddrPort, info TFOInfo) {
+	switch info.ClientFail {
+	case TFOClientFailDataNotAcked, TFOClientFailSYNRetransmitted:
+		h.MarkFailed(ap)
+	}
+}
+
+// Reset removes all recorded failures.
+func (h *HealthCache) Reset() {
+	h.mu.Lock()
+	h.entries = nil
+	h.nextSweep = time.Time{}
+	h.mu.Unlock()
+}
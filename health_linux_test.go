@@ -0,0 +1,66 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+// TestDialHealthCacheObserve ensures that data in SYN the destination did not acknowledge
+// is recorded in [Dialer.HealthCache] by every dial API, not only [Dialer.DialContextInfo].
+func TestDialHealthCacheObserve(t *testing.T) {
+	// Without TFO, the server ignores the data in SYN.
+	lc := ListenConfig{DisableTFO: true}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &discardTCPServer{ln: ln.(*net.TCPListener)}
+	s.Start(t)
+	defer s.Close()
+
+	ap := s.Addr().AddrPort()
+	address := ap.String()
+
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+	}{
+		{"Default", runtimeFallbackAsIs},
+		{"LinuxSendto", runtimeFallbackSetDialLinuxSendto},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+
+			for _, dial := range []struct {
+				name string
+				fn   func(d *Dialer) (net.Conn, error)
+			}{
+				{"Dial", func(d *Dialer) (net.Conn, error) {
+					return d.Dial("tcp", address, hello)
+				}},
+				{"DialContext", func(d *Dialer) (net.Conn, error) {
+					return d.DialContext(context.Background(), "tcp", address, hello)
+				}},
+				{"DialHappyEyeballs", func(d *Dialer) (net.Conn, error) {
+					d.HappyEyeballs = &HappyEyeballs{}
+					return d.Dial("tcp", address, hello)
+				}},
+			} {
+				t.Run(dial.name, func(t *testing.T) {
+					var h HealthCache
+					// Send the data in SYN without waiting for a cookie.
+					d := Dialer{Fallback: true, NoCookie: true, HealthCache: &h}
+					c, err := dial.fn(&d)
+					if err != nil {
+						t.Fatal(err)
+					}
+					c.Close()
+					if h.Healthy(ap) {
+						t.Error("unacknowledged data in SYN was not recorded")
+					}
+				})
+			}
+		})
+	}
+}
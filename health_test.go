@@ -0,0 +1,127 @@
+package tfo
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestHealthCache(t *testing.T) {
+	h := HealthCache{
+		TTL:           50 * time.Millisecond,
+		IPv4PrefixLen: 24,
+	}
+
+	a := netip.MustParseAddrPort("192.0.2.1:443")
+	sameNet := netip.MustParseAddrPort("192.0.2.200:443")
+	mapped := netip.MustParseAddrPort("[::ffff:192.0.2.1]:443")
+	otherPort := netip.MustParseAddrPort("192.0.2.1:80")
+	otherNet := netip.MustParseAddrPort("192.0.3.1:443")
+	v6 := netip.MustParseAddrPort("[2001:db8::1]:443")
+	v6Other := netip.MustParseAddrPort("[2001:db8::2]:443")
+
+	if !h.Healthy(a) {
+		t.Fatal("zero HealthCache reported a destination as unhealthy")
+	}
+
+	h.MarkFailed(a)
+	h.MarkFailed(v6)
+
+	for _, c := range []struct {
+		ap   netip.AddrPort
+		want bool
+	}{
+		{a, false},
+		{sameNet, false},
+		{mapped, false},
+		{otherPort, true},
+		{otherNet, true},
+		{v6, false},
+		{v6Other, true},
+	} {
+		if got := h.Healthy(c.ap); got != c.want {
+			t.Errorf("Healthy(%v) = %t, want %t", c.ap, got, c.want)
+		}
+	}
+
+	time.Sleep(2 * h.TTL)
+	if !h.Healthy(a) {
+		t.Error("entry did not expire after TTL")
+	}
+
+	h.MarkFailed(a)
+	h.MarkHealthy(sameNet)
+	if !h.Healthy(a) {
+		t.Error("MarkHealthy did not remove the entry")
+	}
+
+	h.Observe(a, TFOInfo{Established: true, SYNDataAcked: true})
+	if !h.Healthy(a) {
+		t.Error("Observe recorded a failure for acknowledged data")
+	}
+	h.Observe(a, TFOInfo{Established: true, ClientFail: TFOClientFailSYNRetransmitted})
+	if h.Healthy(a) {
+		t.Error("Observe did not record a failure for a retransmitted SYN")
+	}
+
+	h.Reset()
+	if !h.Healthy(a) {
+		t.Error("Reset did not remove the entry")
+	}
+}
+
+// TestDialHealthCache ensures that a destination with a recorded failure
+// is dialed without TFO, while other destinations are not affected.
+func TestDialHealthCache(t *testing.T) {
+	if comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	s, err := newDiscardTCPServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(t)
+	defer s.Close()
+
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+	}{
+		{"Default", runtimeFallbackAsIs},
+		{"LinuxSendto", runtimeFallbackSetDialLinuxSendto},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+			testDialHealthCache(t, s.Addr().AddrPort())
+		})
+	}
+}
+
+func testDialHealthCache(t *testing.T, ap netip.AddrPort) {
+	address := ap.String()
+
+	var h HealthCache
+	h.MarkFailed(ap)
+
+	d := Dialer{Fallback: true, HealthCache: &h}
+	c, info, err := d.DialContextInfo(context.Background(), "tcp", address, hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if info.Method != DialMethodFallback {
+		t.Errorf("info.Method = %v for an unhealthy destination, want %v", info.Method, DialMethodFallback)
+	}
+
+	h.MarkHealthy(ap)
+	c, info, err = d.DialContextInfo(context.Background(), "tcp", address, hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if d.TFO() && info.Method == DialMethodFallback {
+		t.Errorf("info.Method = %v for a healthy destination", info.Method)
+	}
+}
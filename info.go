@@ -0,0 +1,220 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// DialMethod identifies the path a dial call took to establish a connection.
+type DialMethod uint8
+
+const (
+	// DialMethodNoTFO means TFO was not attempted, because there was no data to send,
+	// no data was safe to send in SYN, TFO was disabled, the network is not TCP,
+	// or another connection attempt was carrying the data in SYN.
+	DialMethodNoTFO DialMethod = iota
+
+	// DialMethodFastOpenConnect means the connection was dialed with the TCP_FASTOPEN_CONNECT
+	// socket option on Linux, and the data was written after connect(2) returned.
+	DialMethodFastOpenConnect
+
+	// DialMethodSendmsg means the data was passed to the kernel along with the connection request,
+	// using sendmsg(MSG_FASTOPEN) on Linux, sendmsg(2) on FreeBSD, connectx(2) on macOS,
+	// or ConnectEx on Windows.
+	DialMethodSendmsg
+
+	// DialMethodFallback means TFO was not available, and the connection was dialed
+	// without TFO, with the data written after the handshake.
+	DialMethodFallback
+)
+
+// String returns the string representation of the dial method.
+func (m DialMethod) String() string {
+	switch m {
+	case DialMethodNoTFO:
+		return "no TFO"
+	case DialMethodFastOpenConnect:
+		return "TCP_FASTOPEN_CONNECT"
+	case DialMethodSendmsg:
+		return "sendmsg"
+	case DialMethodFallback:
+		return "fallback"
+	default:
+		return "DialMethod(" + strconv.Itoa(int(m)) + ")"
+	}
+}
+
+// DialInfo describes how a connection returned by [Dialer.DialContextInfo] was established.
+type DialInfo struct {
+	// Method is the path taken to establish the connection.
+	Method DialMethod
+
+	// SYNDataLen is the number of bytes handed to the kernel along with the connection request.
+	// The kernel carries them in the SYN if it has a TFO cookie for the destination.
+	// It is 0 when the data was written after the handshake.
+	SYNDataLen int
+
+	// TFOInfo is the state of the connection as reported by the kernel when the dial call returned.
+	// It is only populated on Linux. If the handshake had not completed at that point,
+	// call [GetTFOInfo] later to find out whether the peer acknowledged the data in SYN.
+	TFOInfo TFOInfo
+
+	// FallbackReason is the reason the connection was dialed without TFO,
+	// when Method is [DialMethodFallback].
+	FallbackReason FallbackReason
+
+	// FallbackError is the error that caused the fallback, if any.
+	FallbackError *TFOError
+
+	// RacedWithoutSYNData reports whether the connection was established by an attempt
+	// made without TFO while another attempt carried the data in SYN,
+	// as with [Dialer.SingleSYNDataRacer]. The data was written after the handshake.
+	RacedWithoutSYNData bool
+}
+
+// TFOClientFail is the reason a client-side TFO attempt failed, as reported by
+// tcpi_fastopen_client_fail on Linux 5.5 and later.
+type TFOClientFail uint8
+
+const (
+	// TFOClientFailUnspec means no failure was recorded, or the kernel does not report it.
+	TFOClientFailUnspec TFOClientFail = iota
+
+	// TFOClientFailCookieUnavailable means no TFO cookie was available for the destination,
+	// so the SYN carried a cookie request instead of data.
+	TFOClientFailCookieUnavailable
+
+	// TFOClientFailDataNotAcked means the SYN-ACK did not acknowledge the data in SYN.
+	TFOClientFailDataNotAcked
+
+	// TFOClientFailSYNRetransmitted means the SYN was retransmitted without data after a timeout.
+	TFOClientFailSYNRetransmitted
+)
+
+// String returns the string representation of the failure reason.
+func (f TFOClientFail) String() string {
+	switch f {
+	case TFOClientFailUnspec:
+		return "unspecified"
+	case TFOClientFailCookieUnavailable:
+		return "cookie unavailable"
+	case TFOClientFailDataNotAcked:
+		return "data not acked"
+	case TFOClientFailSYNRetransmitted:
+		return "SYN retransmitted"
+	default:
+		return "TFOClientFail(" + strconv.Itoa(int(f)) + ")"
+	}
+}
+
+// TFOInfo is the TFO-related state of a TCP connection as reported by the kernel.
+type TFOInfo struct {
+	// Established reports whether the three-way handshake has completed.
+	Established bool
+
+	// SYNDataAcked reports whether the peer acknowledged the data in SYN.
+	SYNDataAcked bool
+
+	// ClientFail is the reason the client-side TFO attempt failed, if any.
+	ClientFail TFOClientFail
+}
+
+// GetTFOInfo returns the TFO-related state of the TCP socket.
+// It is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func GetTFOInfo(fd uintptr) (TFOInfo, error) {
+	return getTFOInfo(fd) // tcpinfo_linux.go, tcpinfo_stub.go
+}
+
+// ConnTFOInfo is like [GetTFOInfo] but takes a [*net.TCPConn].
+func ConnTFOInfo(c *net.TCPConn) (info TFOInfo, err error) {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return TFOInfo{}, err
+	}
+	if cerr := rawConn.Control(func(fd uintptr) {
+		info, err = getTFOInfo(fd)
+	}); cerr != nil {
+		return TFOInfo{}, cerr
+	}
+	return info, err
+}
+
+// DialContextInfo is like [Dialer.DialContext] but also returns information about
+// how the connection was established.
+func (d *Dialer) DialContextInfo(ctx context.Context, network, address string, b []byte) (net.Conn, DialInfo, error) {
+	var info DialInfo
+	bufs := getSingleBuffer(b)
+	c, err := d.dialContext(ctx, network, address, bufs[:], &info)
+	putSingleBuffer(bufs)
+	if err != nil {
+		return nil, DialInfo{}, err
+	}
+	if tc, ok := c.(*net.TCPConn); ok {
+		info.TFOInfo, _ = ConnTFOInfo(tc)
+	}
+	return c, info, nil
+}
+
+// AcceptInfo describes how an accepted connection was established.
+type AcceptInfo struct {
+	// TFO reports whether the connection was established via TFO,
+	// with a valid cookie and data in SYN.
+	TFO bool
+
+	// SYNDataLen is the number of bytes that came with the SYN.
+	//
+	// The kernel does not report the length of the data in SYN separately, so this is
+	// the number of bytes received when the information was collected. It is an upper bound:
+	// data the client sent after the handshake and that arrived before then is counted too.
+	SYNDataLen int
+}
+
+// GetAcceptInfo returns how the accepted TCP socket was established.
+// It should be called right after accept, as [AcceptInfo.SYNDataLen] also counts
+// data that arrived after the SYN.
+// It is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func GetAcceptInfo(fd uintptr) (AcceptInfo, error) {
+	return getAcceptInfo(fd) // tcpinfo_linux.go, tcpinfo_stub.go
+}
+
+// ConnAcceptInfo is like [GetAcceptInfo] but takes a [*net.TCPConn].
+func ConnAcceptInfo(c *net.TCPConn) (info AcceptInfo, err error) {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return AcceptInfo{}, err
+	}
+	if cerr := rawConn.Control(func(fd uintptr) {
+		info, err = getAcceptInfo(fd)
+	}); cerr != nil {
+		return AcceptInfo{}, cerr
+	}
+	return info, err
+}
+
+// InfoListener wraps a [*net.TCPListener] and reports how each accepted connection
+// was established.
+type InfoListener struct {
+	*net.TCPListener
+}
+
+// NewInfoListener returns a new [InfoListener] that wraps ln.
+func NewInfoListener(ln *net.TCPListener) *InfoListener {
+	return &InfoListener{TCPListener: ln}
+}
+
+// AcceptTCPInfo accepts the next incoming connection and returns it
+// along with information about how it was established.
+//
+// The information is collected before the connection is returned, so that
+// [AcceptInfo.SYNDataLen] is not skewed by data that arrives after accept.
+// It may still count data that arrived between the handshake and accept.
+// If it cannot be collected, the zero value is returned along with the connection.
+func (l *InfoListener) AcceptTCPInfo() (*net.TCPConn, AcceptInfo, error) {
+	c, err := l.AcceptTCP()
+	if err != nil {
+		return nil, AcceptInfo{}, err
+	}
+	info, _ := ConnAcceptInfo(c)
+	return c, info, nil
+}
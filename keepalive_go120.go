@@ -0,0 +1,18 @@
+//go:build (darwin || freebsd || linux || windows) && !go1.23
+
+package tfo
+
+import "net"
+
+// setKeepAlive configures keep-alive probes on c the way [net.Dialer] does,
+// following [net.Dialer.KeepAlive].
+func setKeepAlive(c *net.TCPConn, d *net.Dialer) {
+	if d.KeepAlive >= 0 {
+		c.SetKeepAlive(true)
+		ka := d.KeepAlive
+		if d.KeepAlive == 0 {
+			ka = defaultTCPKeepAlive
+		}
+		c.SetKeepAlivePeriod(ka)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build (darwin || freebsd || linux || windows) && go1.23
+
+package tfo
+
+import "net"
+
+// setKeepAlive configures keep-alive probes on c the way [net.Dialer] does,
+// following [net.Dialer.KeepAlive] and [net.Dialer.KeepAliveConfig].
+func setKeepAlive(c *net.TCPConn, d *net.Dialer) {
+	cfg := d.KeepAliveConfig
+	if !cfg.Enable && d.KeepAlive >= 0 {
+		cfg = net.KeepAliveConfig{
+			Enable: true,
+			Idle:   d.KeepAlive,
+		}
+	}
+	if cfg.Enable {
+		c.SetKeepAliveConfig(cfg)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build go1.23
+
+package tfo
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var shortKeepAliveConfig = net.KeepAliveConfig{
+	Enable:   true,
+	Idle:     7 * time.Second,
+	Interval: 3 * time.Second,
+	Count:    4,
+}
+
+func checkKeepAliveConfig(t *testing.T, c *net.TCPConn, want net.KeepAliveConfig) {
+	t.Helper()
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keepAlive, idle, interval, count int
+	if cerr := rawConn.Control(func(fd uintptr) {
+		if keepAlive, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE); err != nil {
+			return
+		}
+		if idle, err = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE); err != nil {
+			return
+		}
+		if interval, err = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPINTVL); err != nil {
+			return
+		}
+		count, err = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPCNT)
+	}); cerr != nil {
+		t.Fatal(cerr)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keepAlive == 0 {
+		t.Error("SO_KEEPALIVE is not set")
+	}
+	if got := time.Duration(idle) * time.Second; got != want.Idle {
+		t.Errorf("TCP_KEEPIDLE = %v, want %v", got, want.Idle)
+	}
+	if got := time.Duration(interval) * time.Second; got != want.Interval {
+		t.Errorf("TCP_KEEPINTVL = %v, want %v", got, want.Interval)
+	}
+	if count != want.Count {
+		t.Errorf("TCP_KEEPCNT = %d, want %d", count, want.Count)
+	}
+}
+
+// TestKeepAliveConfig ensures that [net.KeepAliveConfig] is applied to dialed connections
+// on every dial path, and to accepted connections.
+func TestKeepAliveConfig(t *testing.T) {
+	for _, c := range cases {
+		c.Run(t, testKeepAliveConfig)
+	}
+}
+
+func testKeepAliveConfig(t *testing.T, lc ListenConfig, d Dialer) {
+	lc.KeepAliveConfig = shortKeepAliveConfig
+	d.KeepAliveConfig = shortKeepAliveConfig
+
+	ln, err := lc.ListenTCP(context.Background(), "tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		checkKeepAliveConfig(t, conn, shortKeepAliveConfig)
+		readUntilEOF(conn, hello, t)
+	}()
+
+	for _, he := range []*HappyEyeballs{nil, {}} {
+		d.HappyEyeballs = he
+		c, err := d.Dial("tcp", ln.Addr().String(), hello)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkKeepAliveConfig(t, c.(*net.TCPConn), shortKeepAliveConfig)
+		if he == nil {
+			c.(*net.TCPConn).CloseWrite()
+			<-ctrlCh
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,85 @@
+package tfo
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+)
+
+// TFOKeyLen is the length of a TFO cookie key in bytes.
+const TFOKeyLen = 16
+
+// TFOKey is a key used by the server to generate and validate TFO cookies.
+type TFOKey [TFOKeyLen]byte
+
+var (
+	errNoTFOKeys      = errors.New("no TFO key given")
+	errTooManyTFOKeys = errors.New("too many TFO keys: at most a primary and a backup key are allowed")
+)
+
+// String returns the key in the format used by the net.ipv4.tcp_fastopen_key sysctl on Linux,
+// which is 4 groups of 8 hex digits separated by dashes, e.g. "00000000-00000000-00000000-00000000".
+func (k TFOKey) String() string {
+	var sb strings.Builder
+	sb.Grow(4*8 + 3)
+	for i := 0; i < TFOKeyLen; i += 4 {
+		if i > 0 {
+			sb.WriteByte('-')
+		}
+		var w [4]byte
+		binary.BigEndian.PutUint32(w[:], binary.LittleEndian.Uint32(k[i:]))
+		sb.WriteString(hex.EncodeToString(w[:]))
+	}
+	return sb.String()
+}
+
+// ParseTFOKey parses a key in the format returned by [TFOKey.String].
+func ParseTFOKey(s string) (k TFOKey, err error) {
+	words := strings.Split(s, "-")
+	if len(words) != 4 {
+		return k, errors.New("invalid TFO key: " + s)
+	}
+	for i, word := range words {
+		var w [4]byte
+		if len(word) != 8 {
+			return k, errors.New("invalid TFO key: " + s)
+		}
+		if _, err = hex.Decode(w[:], []byte(word)); err != nil {
+			return k, errors.New("invalid TFO key: " + s)
+		}
+		binary.LittleEndian.PutUint32(k[i*4:], binary.BigEndian.Uint32(w[:]))
+	}
+	return k, nil
+}
+
+// SetListenerTFOKeys sets the TFO cookie keys of the listener.
+// See [SetTFOKeys] for the meaning of keys.
+func SetListenerTFOKeys(ln *net.TCPListener, keys ...TFOKey) error {
+	rawConn, err := ln.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if cerr := rawConn.Control(func(fd uintptr) {
+		err = setTFOKeys(fd, keys)
+	}); cerr != nil {
+		return cerr
+	}
+	return wrapSyscallError("setsockopt(TCP_FASTOPEN_KEY)", err)
+}
+
+// ListenerTFOKeys returns the TFO cookie keys of the listener.
+// See [GetTFOKeys] for details.
+func ListenerTFOKeys(ln *net.TCPListener) (keys []TFOKey, err error) {
+	rawConn, err := ln.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	if cerr := rawConn.Control(func(fd uintptr) {
+		keys, err = getTFOKeys(fd)
+	}); cerr != nil {
+		return nil, cerr
+	}
+	return keys, wrapSyscallError("getsockopt(TCP_FASTOPEN_KEY)", err)
+}
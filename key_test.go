@@ -0,0 +1,173 @@
+package tfo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+var (
+	testTFOKey0 = TFOKey{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	testTFOKey1 = TFOKey{0xf0, 0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8, 0xf9, 0xfa, 0xfb, 0xfc, 0xfd, 0xfe, 0xff}
+)
+
+func TestTFOKeyStringParse(t *testing.T) {
+	const want = "03020100-07060504-0b0a0908-0f0e0d0c"
+	if got := testTFOKey0.String(); got != want {
+		t.Errorf("testTFOKey0.String() = %q, want %q", got, want)
+	}
+
+	k, err := ParseTFOKey(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != testTFOKey0 {
+		t.Errorf("ParseTFOKey(%q) = %v, want %v", want, k, testTFOKey0)
+	}
+
+	for _, s := range []string{
+		"",
+		"03020100-07060504-0b0a0908",
+		"03020100-07060504-0b0a0908-0f0e0d0",
+		"03020100-07060504-0b0a0908-0f0e0d0g",
+		"03020100-07060504-0b0a0908-0f0e0d0c-00000000",
+	} {
+		if _, err := ParseTFOKey(s); err == nil {
+			t.Errorf("ParseTFOKey(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestListenTFOKeys(t *testing.T) {
+	if comptimeListenNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	lc := ListenConfig{TFOKeys: []TFOKey{testTFOKey0, testTFOKey1}}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip("TFO keys not supported on the current platform")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	lntcp := ln.(*net.TCPListener)
+	defer lntcp.Close()
+
+	keys, err := ListenerTFOKeys(lntcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != testTFOKey0 || keys[1] != testTFOKey1 {
+		t.Fatalf("ListenerTFOKeys() = %v, want [%v %v]", keys, testTFOKey0, testTFOKey1)
+	}
+
+	if err = SetListenerTFOKeys(lntcp, testTFOKey1); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err = ListenerTFOKeys(lntcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != testTFOKey1 {
+		t.Fatalf("ListenerTFOKeys() = %v, want [%v]", keys, testTFOKey1)
+	}
+
+	if err = SetListenerTFOKeys(lntcp); err == nil {
+		t.Error("SetListenerTFOKeys() with no keys succeeded, want error")
+	}
+	if err = SetListenerTFOKeys(lntcp, testTFOKey0, testTFOKey1, testTFOKey0); err == nil {
+		t.Error("SetListenerTFOKeys() with 3 keys succeeded, want error")
+	}
+}
+
+func TestKeyRotatorKeys(t *testing.T) {
+	r0 := KeyRotator{Secret: []byte("secret"), Period: time.Hour}
+	r1 := KeyRotator{Secret: []byte("secret"), Period: time.Hour}
+	r2 := KeyRotator{Secret: []byte("another secret"), Period: time.Hour}
+
+	now := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	primary0, backup0 := r0.Keys(now)
+	if primary0 == backup0 {
+		t.Error("primary key equals backup key")
+	}
+
+	if primary1, backup1 := r1.Keys(now.Add(20 * time.Minute)); primary1 != primary0 || backup1 != backup0 {
+		t.Error("rotators sharing the same secret derived different keys in the same epoch")
+	}
+
+	if primary1, backup1 := r1.Keys(now.Add(time.Hour)); backup1 != primary0 || primary1 == primary0 {
+		t.Error("primary key of the previous epoch is not the backup key of the next epoch")
+	}
+
+	if primary2, _ := r2.Keys(now); primary2 == primary0 {
+		t.Error("rotators with different secrets derived the same key")
+	}
+
+	before := time.Unix(0, 0).Add(-time.Nanosecond)
+	if e := r0.epoch(before); e != -1 {
+		t.Errorf("r0.epoch(%v) = %d, want -1", before, e)
+	}
+	if d := r0.untilNextEpoch(now); d != 30*time.Minute {
+		t.Errorf("r0.untilNextEpoch(%v) = %v, want 30m", now, d)
+	}
+}
+
+func TestKeyRotatorRotate(t *testing.T) {
+	if comptimeListenNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	r := KeyRotator{Secret: []byte("secret"), Period: 50 * time.Millisecond}
+	defer r.Close()
+
+	lc := ListenConfig{KeyRotator: &r}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip("TFO keys not supported on the current platform")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	lntcp := ln.(*net.TCPListener)
+	defer lntcp.Close()
+
+	keys, err := ListenerTFOKeys(lntcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(keys))
+	}
+
+	time.Sleep(3 * r.Period)
+
+	newKeys, err := ListenerTFOKeys(lntcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newKeys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(newKeys))
+	}
+	if newKeys[0] == keys[0] {
+		t.Error("keys were not rotated")
+	}
+	// The timer may not have fired yet for the current epoch.
+	if primary, backup := r.Keys(time.Now()); newKeys[0] != primary && newKeys[0] != backup {
+		t.Errorf("installed primary key %v does not match the current epoch", newKeys[0])
+	}
+
+	lntcp.Close()
+	time.Sleep(2 * r.Period)
+
+	r.mu.Lock()
+	n := len(r.listeners)
+	r.mu.Unlock()
+	if n != 0 {
+		t.Errorf("closed listener is still registered")
+	}
+}
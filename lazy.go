@@ -0,0 +1,332 @@
+package tfo
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// lazyReadFromBufferSize is the size of the buffer [LazyConn.ReadFrom] reads
+// the data to send in SYN into.
+const lazyReadFromBufferSize = 32 * 1024
+
+// LazyConn is a connection returned by [Dialer.DialLazy].
+// The connection is established by the first call to one of its methods
+// that needs it. If that call is Write or ReadFrom, the data being written
+// is sent in SYN.
+type LazyConn struct {
+	d       Dialer
+	ctx     context.Context
+	cancel  context.CancelFunc
+	network string
+	address string
+
+	// done is closed when the dial call returns.
+	// conn, info and err are immutable once done is closed.
+	done chan struct{}
+	conn net.Conn
+	info DialInfo
+	err  error
+
+	mu            sync.Mutex
+	started       bool
+	closed        bool
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// DialLazy returns a connection to the address on the named network,
+// without establishing it until the first Write, so that the written data
+// is sent in SYN. A Read, CloseWrite or SyscallConn call before any Write
+// establishes the connection without data.
+//
+// ctx is used for the deferred dial call, and must not be canceled
+// before the connection is established.
+func (d *Dialer) DialLazy(ctx context.Context, network, address string) *LazyConn {
+	if ctx == nil {
+		panic("nil context")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &LazyConn{
+		d:       *d,
+		ctx:     ctx,
+		cancel:  cancel,
+		network: network,
+		address: address,
+		done:    make(chan struct{}),
+	}
+}
+
+// connect establishes the connection with b as the data in SYN,
+// or waits for the connection established by another call.
+// It returns whether b was written.
+func (c *LazyConn) connect(op string, b []byte, deadline time.Time) (conn net.Conn, written bool, err error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, false, &net.OpError{Op: op, Net: c.network, Source: nil, Addr: nil, Err: net.ErrClosed}
+	}
+	if c.started {
+		c.mu.Unlock()
+		<-c.done
+		return c.conn, false, c.err
+	}
+	c.started = true
+	c.mu.Unlock()
+
+	ctx := c.ctx
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	c.conn, c.info, c.err = c.d.DialContextInfo(ctx, c.network, c.address, b)
+	// The established connection does not depend on the dial context.
+	c.cancel()
+
+	// Deadlines set after this point are applied directly by the setters.
+	c.mu.Lock()
+	if c.err == nil {
+		if !c.readDeadline.IsZero() {
+			c.conn.SetReadDeadline(c.readDeadline)
+		}
+		if !c.writeDeadline.IsZero() {
+			c.conn.SetWriteDeadline(c.writeDeadline)
+		}
+	}
+	close(c.done)
+	c.mu.Unlock()
+
+	return c.conn, true, c.err
+}
+
+// established returns the connection if the dial call has returned.
+func (c *LazyConn) established() (net.Conn, bool) {
+	select {
+	case <-c.done:
+		return c.conn, c.err == nil
+	default:
+		return nil, false
+	}
+}
+
+// Info returns information about how the connection was established.
+// It returns false if the connection has not been successfully established.
+func (c *LazyConn) Info() (DialInfo, bool) {
+	if _, ok := c.established(); !ok {
+		return DialInfo{}, false
+	}
+	return c.info, true
+}
+
+// Read implements [net.Conn.Read].
+// If the connection has not been established, it is established without data.
+func (c *LazyConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	deadline := c.readDeadline
+	c.mu.Unlock()
+
+	conn, _, err := c.connect("read", nil, deadline)
+	if err != nil {
+		return 0, err
+	}
+	return conn.Read(b)
+}
+
+// Write implements [net.Conn.Write].
+// If the connection has not been established, it is established with b as the data in SYN.
+func (c *LazyConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	deadline := c.writeDeadline
+	c.mu.Unlock()
+
+	conn, written, err := c.connect("write", b, deadline)
+	if err != nil {
+		return 0, err
+	}
+	if written {
+		return len(b), nil
+	}
+	return conn.Write(b)
+}
+
+// ReadFrom implements [io.ReaderFrom].
+// If the connection has not been established, the first read from r is sent in SYN.
+// If the connection is closed, or failed to be established by another call,
+// nothing is read from r.
+func (c *LazyConn) ReadFrom(r io.Reader) (n int64, err error) {
+	c.mu.Lock()
+	dial := !c.started && !c.closed
+	c.mu.Unlock()
+
+	if dial {
+		buf := make([]byte, lazyReadFromBufferSize)
+		nr, rerr := r.Read(buf)
+		if nr > 0 {
+			nw, werr := c.Write(buf[:nr])
+			n += int64(nw)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+
+	conn, _, err := c.connect("readfrom", nil, time.Time{})
+	if err != nil {
+		return n, err
+	}
+	var nn int64
+	if rf, ok := conn.(io.ReaderFrom); ok {
+		nn, err = rf.ReadFrom(r)
+	} else {
+		nn, err = io.Copy(conn, r)
+	}
+	return n + nn, err
+}
+
+// Close implements [net.Conn.Close].
+// If a dial call is in progress, it is canceled.
+func (c *LazyConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return &net.OpError{Op: "close", Net: c.network, Source: nil, Addr: nil, Err: net.ErrClosed}
+	}
+	c.closed = true
+	started := c.started
+	c.mu.Unlock()
+
+	c.cancel()
+	if !started {
+		return nil
+	}
+	<-c.done
+	if c.err != nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// CloseWrite shuts down the writing side of the TCP connection.
+// If the connection has not been established, it is established without data.
+func (c *LazyConn) CloseWrite() error {
+	conn, _, err := c.connect("close", nil, time.Time{})
+	if err != nil {
+		return err
+	}
+	cw, ok := conn.(interface{ CloseWrite() error })
+	if !ok {
+		return &net.OpError{Op: "close", Net: c.network, Source: conn.LocalAddr(), Addr: conn.RemoteAddr(), Err: ErrUnsupported}
+	}
+	return cw.CloseWrite()
+}
+
+// SyscallConn returns a raw network connection.
+// If the connection has not been established, it is established without data.
+func (c *LazyConn) SyscallConn() (syscall.RawConn, error) {
+	conn, _, err := c.connect("raw-control", nil, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return sc.SyscallConn()
+}
+
+// lazyAddr is the address a [LazyConn] that has not been established is dialing,
+// when it is not an IP literal.
+type lazyAddr struct {
+	network string
+	address string
+}
+
+// Network implements [net.Addr.Network].
+func (a *lazyAddr) Network() string {
+	return a.network
+}
+
+// String implements [net.Addr.String].
+func (a *lazyAddr) String() string {
+	return a.address
+}
+
+// LocalAddr implements [net.Conn.LocalAddr].
+// If the connection has not been established, it returns [net.Dialer.LocalAddr],
+// or an unspecified [*net.TCPAddr] if that is not set.
+func (c *LazyConn) LocalAddr() net.Addr {
+	if conn, ok := c.established(); ok {
+		return conn.LocalAddr()
+	}
+	if c.d.LocalAddr != nil {
+		return c.d.LocalAddr
+	}
+	return &net.TCPAddr{}
+}
+
+// RemoteAddr implements [net.Conn.RemoteAddr].
+// If the connection has not been established, it returns the address being dialed,
+// as a [*net.TCPAddr] if it is an IP literal, or as is otherwise.
+func (c *LazyConn) RemoteAddr() net.Addr {
+	if conn, ok := c.established(); ok {
+		return conn.RemoteAddr()
+	}
+	if ap, err := netip.ParseAddrPort(c.address); err == nil {
+		return net.TCPAddrFromAddrPort(ap)
+	}
+	return &lazyAddr{network: c.network, address: c.address}
+}
+
+// SetDeadline implements [net.Conn.SetDeadline].
+// If the connection has not been established, the deadline also bounds the dial call.
+func (c *LazyConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	conn, ok := c.established()
+	c.mu.Unlock()
+	if ok {
+		return conn.SetDeadline(t)
+	}
+	return nil
+}
+
+// SetReadDeadline implements [net.Conn.SetReadDeadline].
+// If the connection has not been established, the deadline also bounds
+// the dial call started by Read.
+func (c *LazyConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	conn, ok := c.established()
+	c.mu.Unlock()
+	if ok {
+		return conn.SetReadDeadline(t)
+	}
+	return nil
+}
+
+// SetWriteDeadline implements [net.Conn.SetWriteDeadline].
+// If the connection has not been established, the deadline also bounds
+// the dial call started by Write.
+func (c *LazyConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	conn, ok := c.established()
+	c.mu.Unlock()
+	if ok {
+		return conn.SetWriteDeadline(t)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package tfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func listenLazyTest(t *testing.T) *net.TCPListener {
+	t.Helper()
+	lc := ListenConfig{DisableTFO: comptimeListenNoTFO}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln.(*net.TCPListener)
+}
+
+func newLazyTestDialer() Dialer {
+	return Dialer{DisableTFO: comptimeDialNoTFO}
+}
+
+// TestLazyConnWriteFirst ensures that the first Write on a [LazyConn]
+// establishes the connection and delivers the data.
+func TestLazyConnWriteFirst(t *testing.T) {
+	ln := listenLazyTest(t)
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, helloworld, t)
+		write(conn, worldhello, t)
+		conn.CloseWrite()
+	}()
+
+	d := newLazyTestDialer()
+	c := d.DialLazy(context.Background(), "tcp", ln.Addr().String())
+	defer c.Close()
+
+	if _, ok := c.Info(); ok {
+		t.Error("Info() reported an established connection before the first Write")
+	}
+	if laddr := c.LocalAddr(); laddr == nil || laddr.String() != ":0" {
+		t.Errorf("LocalAddr() = %v before the first Write, want :0", laddr)
+	}
+	if raddr, ok := c.RemoteAddr().(*net.TCPAddr); !ok || raddr.String() != ln.Addr().String() {
+		t.Errorf("RemoteAddr() = %v before the first Write, want %v", c.RemoteAddr(), ln.Addr())
+	}
+	unresolved := d.DialLazy(context.Background(), "tcp", "example.com:443")
+	if raddr := unresolved.RemoteAddr(); raddr == nil || raddr.String() != "example.com:443" || raddr.Network() != "tcp" {
+		t.Errorf("RemoteAddr() = %v for an unresolved address, want example.com:443", raddr)
+	}
+	unresolved.Close()
+
+	write(c, hello, t)
+
+	info, ok := c.Info()
+	if !ok {
+		t.Fatal("Info() reported no connection after the first Write")
+	}
+	t.Logf("info: %+v", info)
+	if !comptimeDialNoTFO && info.Method == DialMethodNoTFO {
+		t.Errorf("info.Method = %v, want a TFO method", info.Method)
+	}
+	if raddr := c.RemoteAddr().(*net.TCPAddr); raddr.Port != ln.Addr().(*net.TCPAddr).Port {
+		t.Errorf("Bad remote addr: %v", raddr)
+	}
+
+	write(c, world, t)
+	if err := c.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	readUntilEOF(c, worldhello, t)
+	testRawConnControl(t, c)
+	<-ctrlCh
+}
+
+// TestLazyConnReadFirst ensures that a Read before any Write on a [LazyConn]
+// establishes the connection without data.
+func TestLazyConnReadFirst(t *testing.T) {
+	ln := listenLazyTest(t)
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		write(conn, hello, t)
+		readUntilEOF(conn, world, t)
+	}()
+
+	d := newLazyTestDialer()
+	c := d.DialLazy(context.Background(), "tcp", ln.Addr().String())
+	defer c.Close()
+
+	readExactlyOneByte(c, 'h', t)
+
+	info, ok := c.Info()
+	if !ok {
+		t.Fatal("Info() reported no connection after Read")
+	}
+	if info.Method != DialMethodNoTFO {
+		t.Errorf("info.Method = %v, want %v", info.Method, DialMethodNoTFO)
+	}
+
+	writeWithReadFrom(c, world, t)
+	c.CloseWrite()
+	<-ctrlCh
+}
+
+// TestLazyConnReadFrom ensures that ReadFrom on a [LazyConn] that has not been
+// established sends all data from the reader.
+func TestLazyConnReadFrom(t *testing.T) {
+	ln := listenLazyTest(t)
+	defer ln.Close()
+
+	payload := bytes.Repeat(helloworld, lazyReadFromBufferSize/len(helloworld)+1)
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, payload, t)
+	}()
+
+	d := newLazyTestDialer()
+	c := d.DialLazy(context.Background(), "tcp", ln.Addr().String())
+	defer c.Close()
+
+	writeWithReadFrom(c, payload, t)
+	c.CloseWrite()
+	<-ctrlCh
+}
+
+// TestLazyConnCloseBeforeDial ensures that a [LazyConn] closed before
+// being established never dials.
+func TestLazyConnCloseBeforeDial(t *testing.T) {
+	ln := listenLazyTest(t)
+	defer ln.Close()
+
+	d := newLazyTestDialer()
+	c := d.DialLazy(context.Background(), "tcp", ln.Addr().String())
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Write(hello); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Write after Close returned %v, want net.ErrClosed", err)
+	}
+	if err := c.Close(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("second Close returned %v, want net.ErrClosed", err)
+	}
+	if _, ok := c.Info(); ok {
+		t.Error("Info() reported an established connection")
+	}
+}
+
+// TestLazyConnReadFromAfterFailedDial ensures that ReadFrom on a [LazyConn]
+// that failed to be established, or that is closed, returns an error without
+// reading from the reader, and that the dial context is released once the dial call returns.
+func TestLazyConnReadFromAfterFailedDial(t *testing.T) {
+	ln := listenLazyTest(t)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	d := newLazyTestDialer()
+	c := d.DialLazy(context.Background(), "tcp", addr)
+	defer c.Close()
+
+	if _, err := c.Read(make([]byte, 1)); err == nil {
+		t.Fatal("Read on a connection to a closed port succeeded")
+	}
+	if c.ctx.Err() == nil {
+		t.Error("dial context not canceled after the dial call returned")
+	}
+
+	r := bytes.NewReader(hello)
+	if n, err := c.ReadFrom(r); err == nil || n != 0 {
+		t.Errorf("ReadFrom() = %d, %v, want 0 and the dial error", n, err)
+	}
+	if r.Len() != len(hello) {
+		t.Errorf("ReadFrom() consumed %d bytes from the reader", len(hello)-r.Len())
+	}
+
+	c = d.DialLazy(context.Background(), "tcp", addr)
+	c.Close()
+	if _, err := c.ReadFrom(r); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("ReadFrom after Close returned %v, want net.ErrClosed", err)
+	}
+	if r.Len() != len(hello) {
+		t.Errorf("ReadFrom after Close consumed %d bytes from the reader", len(hello)-r.Len())
+	}
+}
@@ -0,0 +1,129 @@
+package tfo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TFOCounters are the TFO-related TcpExt MIB counters of the Linux kernel.
+// Counters not reported by the running kernel are zero.
+type TFOCounters struct {
+	// Active is TCPFastOpenActive, the number of SYNs sent with data and a cookie.
+	Active uint64
+
+	// ActiveFail is TCPFastOpenActiveFail, the number of active opens whose data in SYN
+	// was not acknowledged, or whose SYN was retransmitted without data.
+	ActiveFail uint64
+
+	// Passive is TCPFastOpenPassive, the number of connections accepted with data in SYN.
+	Passive uint64
+
+	// PassiveFail is TCPFastOpenPassiveFail, the number of SYNs with data
+	// that were rejected, e.g. because of an invalid cookie.
+	PassiveFail uint64
+
+	// ListenOverflow is TCPFastOpenListenOverflow, the number of SYNs with data that
+	// were handled as regular SYNs because the listener's TFO queue was full.
+	ListenOverflow uint64
+
+	// CookieReqd is TCPFastOpenCookieReqd, the number of SYNs with a cookie request.
+	CookieReqd uint64
+
+	// Blackhole is TCPFastOpenBlackhole, the number of times active TFO was disabled
+	// after a blackhole was detected.
+	Blackhole uint64
+
+	// PassiveAltKey is TCPFastOpenPassiveAltKey, the number of cookies
+	// validated with the backup key.
+	PassiveAltKey uint64
+}
+
+// Sub returns the difference between c and old, counter by counter.
+func (c TFOCounters) Sub(old TFOCounters) TFOCounters {
+	return TFOCounters{
+		Active:         c.Active - old.Active,
+		ActiveFail:     c.ActiveFail - old.ActiveFail,
+		Passive:        c.Passive - old.Passive,
+		PassiveFail:    c.PassiveFail - old.PassiveFail,
+		ListenOverflow: c.ListenOverflow - old.ListenOverflow,
+		CookieReqd:     c.CookieReqd - old.CookieReqd,
+		Blackhole:      c.Blackhole - old.Blackhole,
+		PassiveAltKey:  c.PassiveAltKey - old.PassiveAltKey,
+	}
+}
+
+// ReadTFOCounters reads the TFO counters of the network namespace of the calling thread
+// from the procfs mounted at procRoot. If procRoot is empty, "/proc" is used.
+//
+// This is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func ReadTFOCounters(procRoot string) (TFOCounters, error) {
+	return readTFOCounters(procRoot) // netstat_linux.go, netstat_stub.go
+}
+
+var errNoTcpExt = errors.New("no TcpExt counters")
+
+// counter returns a pointer to the field of c for the counter name.
+func (c *TFOCounters) counter(name string) *uint64 {
+	switch name {
+	case "TCPFastOpenActive":
+		return &c.Active
+	case "TCPFastOpenActiveFail":
+		return &c.ActiveFail
+	case "TCPFastOpenPassive":
+		return &c.Passive
+	case "TCPFastOpenPassiveFail":
+		return &c.PassiveFail
+	case "TCPFastOpenListenOverflow":
+		return &c.ListenOverflow
+	case "TCPFastOpenCookieReqd":
+		return &c.CookieReqd
+	case "TCPFastOpenBlackhole":
+		return &c.Blackhole
+	case "TCPFastOpenPassiveAltKey":
+		return &c.PassiveAltKey
+	default:
+		return nil
+	}
+}
+
+// parseTFOCounters parses the TFO counters from the contents of /proc/net/netstat,
+// where each group of counters is a line of names followed by a line of values,
+// both prefixed with the group name.
+func parseTFOCounters(r io.Reader) (c TFOCounters, err error) {
+	const prefix = "TcpExt:"
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if !strings.HasPrefix(s.Text(), prefix) {
+			continue
+		}
+		names := strings.Fields(s.Text()[len(prefix):])
+		if !s.Scan() {
+			break
+		}
+		if !strings.HasPrefix(s.Text(), prefix) {
+			return TFOCounters{}, errors.New("TcpExt names not followed by values")
+		}
+		values := strings.Fields(s.Text()[len(prefix):])
+		if len(names) != len(values) {
+			return TFOCounters{}, errors.New("TcpExt has " + strconv.Itoa(len(names)) + " names but " + strconv.Itoa(len(values)) + " values")
+		}
+		for i, name := range names {
+			p := c.counter(name)
+			if p == nil {
+				continue
+			}
+			if *p, err = strconv.ParseUint(values[i], 10, 64); err != nil {
+				return TFOCounters{}, err
+			}
+		}
+		return c, nil
+	}
+	if err = s.Err(); err != nil {
+		return TFOCounters{}, err
+	}
+	return TFOCounters{}, errNoTcpExt
+}
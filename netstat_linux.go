@@ -0,0 +1,27 @@
+package tfo
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// netstatPath is relative to the procfs root. thread-self is used instead of self,
+// as threads may be in different network namespaces.
+const netstatPath = "thread-self/net/netstat"
+
+func readTFOCounters(procRoot string) (TFOCounters, error) {
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	path := filepath.Join(procRoot, netstatPath)
+	f, err := os.Open(path)
+	if err != nil {
+		return TFOCounters{}, err
+	}
+	defer f.Close()
+	c, err := parseTFOCounters(f)
+	if err != nil {
+		return TFOCounters{}, &os.PathError{Op: "parse", Path: path, Err: err}
+	}
+	return c, nil
+}
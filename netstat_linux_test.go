@@ -0,0 +1,68 @@
+package tfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testNetstat = `TcpExt: SyncookiesSent TCPFastOpenActive TCPFastOpenActiveFail TCPFastOpenPassive TCPFastOpenPassiveFail TCPFastOpenListenOverflow TCPFastOpenCookieReqd TCPFastOpenBlackhole TCPFastOpenPassiveAltKey TCPSpuriousRtxHostQueues
+TcpExt: 7 1 2 3 4 5 6 7 8 9
+IpExt: InNoRoutes InTruncatedPkts
+IpExt: 0 0
+`
+
+func newTestNetstatProcRoot(t *testing.T, value string) string {
+	t.Helper()
+	procRoot := t.TempDir()
+	path := filepath.Join(procRoot, netstatPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(value), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return procRoot
+}
+
+func TestReadTFOCounters(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		value   string
+		want    TFOCounters
+		wantErr bool
+	}{
+		{"Full", testNetstat, TFOCounters{1, 2, 3, 4, 5, 6, 7, 8}, false},
+		{"Old", "TcpExt: SyncookiesSent TCPFastOpenActive\nTcpExt: 0 42\n", TFOCounters{Active: 42}, false},
+		{"NoTcpExt", "IpExt: InNoRoutes\nIpExt: 0\n", TFOCounters{}, true},
+		{"NoValues", "TcpExt: TCPFastOpenActive\n", TFOCounters{}, true},
+		{"Mismatch", "TcpExt: TCPFastOpenActive TCPFastOpenPassive\nTcpExt: 1\n", TFOCounters{}, true},
+		{"BadValue", "TcpExt: TCPFastOpenActive\nTcpExt: -1\n", TFOCounters{}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ReadTFOCounters(newTestNetstatProcRoot(t, c.value))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ReadTFOCounters() returned error %v, wantErr %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("ReadTFOCounters() = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestTFOCountersSub(t *testing.T) {
+	c := TFOCounters{10, 20, 30, 40, 50, 60, 70, 80}
+	old := TFOCounters{1, 2, 3, 4, 5, 6, 7, 8}
+	if got, want := c.Sub(old), (TFOCounters{9, 18, 27, 36, 45, 54, 63, 72}); got != want {
+		t.Errorf("Sub() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadTFOCountersProc(t *testing.T) {
+	c, err := ReadTFOCounters("")
+	if err != nil {
+		t.Skip("cannot read /proc:", err)
+	}
+	t.Logf("counters: %+v", c)
+}
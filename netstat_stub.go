@@ -0,0 +1,7 @@
+//go:build !linux
+
+package tfo
+
+func readTFOCounters(procRoot string) (TFOCounters, error) {
+	return TFOCounters{}, ErrUnsupported
+}
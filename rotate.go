@@ -0,0 +1,173 @@
+package tfo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultKeyRotationPeriod is the rotation period used by [KeyRotator] when
+// [KeyRotator.Period] is not set.
+const DefaultKeyRotationPeriod = time.Hour
+
+var errKeyRotatorClosed = errors.New("key rotator closed")
+
+// KeyRotator derives TFO cookie keys from a shared secret and the current time,
+// and keeps them installed on registered listeners.
+//
+// Time is divided into epochs of [KeyRotator.Period]. During each epoch, the key
+// derived from the current epoch is installed as the primary key, and the key
+// derived from the previous epoch as the backup key. A cookie is therefore
+// accepted for at least one and at most two periods after it was issued,
+// by every listener whose rotator shares the same secret and period.
+// Instances should have their clocks synchronized, or cookies issued by one instance
+// may be briefly rejected by another around epoch boundaries.
+//
+// Keys are only installed on Linux. See [SetTFOKeys].
+//
+// The exported fields must not be modified after the first call to [KeyRotator.Register].
+type KeyRotator struct {
+	// Secret is the shared secret keys are derived from.
+	Secret []byte
+
+	// Period is the duration of an epoch.
+	// If zero, [DefaultKeyRotationPeriod] is used.
+	Period time.Duration
+
+	// ErrorFunc, if not nil, is called when keys cannot be installed on a registered listener
+	// during rotation. The listener is unregistered before ErrorFunc is called.
+	// Listeners that have been closed are unregistered silently.
+	ErrorFunc func(ln *net.TCPListener, err error)
+
+	mu        sync.Mutex
+	listeners map[*net.TCPListener]struct{}
+	timer     *time.Timer
+	closed    bool
+}
+
+func (r *KeyRotator) period() time.Duration {
+	if r.Period > 0 {
+		return r.Period
+	}
+	return DefaultKeyRotationPeriod
+}
+
+// epoch returns the epoch t falls into.
+func (r *KeyRotator) epoch(t time.Time) int64 {
+	ns, p := t.UnixNano(), int64(r.period())
+	e := ns / p
+	if ns%p < 0 {
+		e--
+	}
+	return e
+}
+
+// untilNextEpoch returns the duration from t to the start of the next epoch.
+func (r *KeyRotator) untilNextEpoch(t time.Time) time.Duration {
+	return time.Duration((r.epoch(t)+1)*int64(r.period()) - t.UnixNano())
+}
+
+// deriveKey derives the key for the given epoch.
+func (r *KeyRotator) deriveKey(epoch int64) (key TFOKey) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(epoch))
+	mac := hmac.New(sha256.New, r.Secret)
+	mac.Write([]byte("tfo-go key rotation"))
+	mac.Write(b[:])
+	copy(key[:], mac.Sum(nil))
+	return key
+}
+
+// Keys returns the primary and backup keys for time t.
+func (r *KeyRotator) Keys(t time.Time) (primary, backup TFOKey) {
+	epoch := r.epoch(t)
+	return r.deriveKey(epoch), r.deriveKey(epoch - 1)
+}
+
+// Register installs the current keys on the listener, and keeps them up to date
+// until the listener is closed or unregistered, or the rotator is closed.
+func (r *KeyRotator) Register(ln *net.TCPListener) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errKeyRotatorClosed
+	}
+
+	now := time.Now()
+	primary, backup := r.Keys(now)
+	if err := SetListenerTFOKeys(ln, primary, backup); err != nil {
+		return err
+	}
+
+	if r.listeners == nil {
+		r.listeners = make(map[*net.TCPListener]struct{})
+	}
+	r.listeners[ln] = struct{}{}
+
+	if r.timer == nil {
+		r.timer = time.AfterFunc(r.untilNextEpoch(now), r.rotate)
+	}
+	return nil
+}
+
+// Unregister stops rotating keys on the listener.
+// The keys already installed on the listener are left in place.
+func (r *KeyRotator) Unregister(ln *net.TCPListener) {
+	r.mu.Lock()
+	delete(r.listeners, ln)
+	r.mu.Unlock()
+}
+
+// Close stops the rotation and unregisters all listeners.
+// The keys already installed on the listeners are left in place.
+func (r *KeyRotator) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.listeners = nil
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	return nil
+}
+
+// rotate installs the keys of the current epoch on all registered listeners,
+// and schedules the next rotation.
+func (r *KeyRotator) rotate() {
+	type listenerError struct {
+		ln  *net.TCPListener
+		err error
+	}
+	var errs []listenerError
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	primary, backup := r.Keys(now)
+	for ln := range r.listeners {
+		if err := SetListenerTFOKeys(ln, primary, backup); err != nil {
+			delete(r.listeners, ln)
+			if !errors.Is(err, net.ErrClosed) {
+				errs = append(errs, listenerError{ln, err})
+			}
+		}
+	}
+	r.timer.Reset(r.untilNextEpoch(now))
+	r.mu.Unlock()
+
+	if r.ErrorFunc != nil {
+		for _, e := range errs {
+			r.ErrorFunc(e.ln, e.err)
+		}
+	}
+}
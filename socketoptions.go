@@ -0,0 +1,223 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// SocketOptions is a set of socket options that dial and listen calls set on every socket
+// they create, whether TFO is used or not. The zero value of each field leaves the option
+// at the system default.
+//
+// The options are set right after the socket is created, before the Control functions
+// of [net.Dialer] and [net.ListenConfig] are called, so they can still be overridden there,
+// and before the socket is bound, connected, or listening. Most systems copy the options
+// of a listener socket to the connections it accepts.
+//
+// An option that is not supported on the current platform makes the call fail
+// with an error that names the option and matches [ErrUnsupported].
+type SocketOptions struct {
+	// Mark sets SO_MARK, the firewall mark of the packets sent by the socket.
+	//
+	// This is only supported on Linux.
+	Mark int
+
+	// BindToDevice sets SO_BINDTODEVICE, which only sends and receives packets
+	// through the network interface with that name.
+	//
+	// This is only supported on Linux.
+	BindToDevice string
+
+	// UserTimeout sets TCP_USER_TIMEOUT, the maximum amount of time transmitted data
+	// may remain unacknowledged before the connection is closed.
+	// It is rounded up to milliseconds.
+	//
+	// This is only supported on Linux.
+	UserTimeout time.Duration
+
+	// CongestionControl sets TCP_CONGESTION, the name of the congestion control algorithm,
+	// such as "bbr" or "cubic".
+	//
+	// This is only supported on Linux and FreeBSD.
+	CongestionControl string
+
+	// TOS sets the type of service field of the packets sent by the socket,
+	// which holds the DSCP and ECN bits. It is set with IP_TOS on IPv4 sockets
+	// and with IPV6_TCLASS on IPv6 sockets.
+	//
+	// This is not supported on Windows.
+	TOS int
+
+	// SendBufferSize sets SO_SNDBUF, the size of the socket's send buffer in bytes.
+	SendBufferSize int
+
+	// ReceiveBufferSize sets SO_RCVBUF, the size of the socket's receive buffer in bytes.
+	// It is set before connect and listen, so it is also taken into account
+	// for the window scale advertised in the handshake.
+	ReceiveBufferSize int
+
+	// NotSentLowat sets TCP_NOTSENT_LOWAT, the amount of unsent data in bytes
+	// above which the socket is not reported writable.
+	//
+	// This is only supported on Linux and macOS.
+	NotSentLowat int
+
+	// Linger sets SO_LINGER. If positive, closing the connection blocks for up to
+	// that long, rounded up to seconds, until the unsent data is sent.
+	// If negative, closing the connection discards the unsent data and resets
+	// the connection, like [net.TCPConn.SetLinger] with 0.
+	Linger time.Duration
+
+	// DisableNoDelay controls whether to leave Nagle's algorithm enabled on dialed connections.
+	// By default, dialed connections set TCP_NODELAY, like connections dialed by [net.Dialer].
+	//
+	// It has no effect on listeners, as [net.TCPListener] sets TCP_NODELAY on accepted
+	// connections. Call [net.TCPConn.SetNoDelay] on them instead.
+	DisableNoDelay bool
+}
+
+// socketOptionError wraps err returned by setting the socket option with the given name.
+func socketOptionError(name string, err error) error {
+	return os.NewSyscallError("setsockopt("+name+")", err)
+}
+
+// durationCeil returns the positive duration d in units of unit, rounded up,
+// so that it is never truncated to 0.
+func durationCeil(d, unit time.Duration) int {
+	return int((d + unit - 1) / unit)
+}
+
+// empty reports whether o leaves all the options that are set on the socket
+// before connect or listen at their defaults.
+func (o *SocketOptions) empty() bool {
+	so := *o
+	so.DisableNoDelay = false
+	return so == SocketOptions{}
+}
+
+// apply sets the options on the socket.
+func (o *SocketOptions) apply(fd uintptr, ipv6 bool) error {
+	if o.Mark != 0 {
+		if err := setMark(fd, o.Mark); err != nil {
+			return socketOptionError("SO_MARK", err)
+		}
+	}
+	if o.BindToDevice != "" {
+		if err := setBindToDevice(fd, o.BindToDevice); err != nil {
+			return socketOptionError("SO_BINDTODEVICE", err)
+		}
+	}
+	if o.UserTimeout > 0 {
+		if err := setUserTimeout(fd, durationCeil(o.UserTimeout, time.Millisecond)); err != nil {
+			return socketOptionError("TCP_USER_TIMEOUT", err)
+		}
+	}
+	if o.CongestionControl != "" {
+		if err := setCongestionControl(fd, o.CongestionControl); err != nil {
+			return socketOptionError("TCP_CONGESTION", err)
+		}
+	}
+	if o.TOS != 0 {
+		if err := setTOS(fd, ipv6, o.TOS); err != nil {
+			if ipv6 {
+				return socketOptionError("IPV6_TCLASS", err)
+			}
+			return socketOptionError("IP_TOS", err)
+		}
+	}
+	if o.SendBufferSize > 0 {
+		if err := setSendBuffer(fd, o.SendBufferSize); err != nil {
+			return socketOptionError("SO_SNDBUF", err)
+		}
+	}
+	if o.ReceiveBufferSize > 0 {
+		if err := setReceiveBuffer(fd, o.ReceiveBufferSize); err != nil {
+			return socketOptionError("SO_RCVBUF", err)
+		}
+	}
+	if o.NotSentLowat > 0 {
+		if err := setNotSentLowat(fd, o.NotSentLowat); err != nil {
+			return socketOptionError("TCP_NOTSENT_LOWAT", err)
+		}
+	}
+	if o.Linger != 0 {
+		var sec int
+		if o.Linger > 0 {
+			sec = durationCeil(o.Linger, time.Second)
+		}
+		if err := setLinger(fd, sec); err != nil {
+			return socketOptionError("SO_LINGER", err)
+		}
+	}
+	return nil
+}
+
+// control sets the options on the socket passed to a Control function.
+func (o *SocketOptions) control(network string, c syscall.RawConn) (err error) {
+	if cerr := c.Control(func(fd uintptr) {
+		err = o.apply(fd, network == "tcp6")
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// controlContext returns the ControlContext function of [net.Dialer] that applies
+// [Dialer.SocketOptions] before calling the Control functions of d.
+func (d *Dialer) controlContext() func(context.Context, string, string, syscall.RawConn) error {
+	if d.SocketOptions.empty() {
+		return d.ControlContext
+	}
+	ctrlCtxFn := d.ControlContext
+	ctrlFn := d.Control
+	return func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		if err := d.SocketOptions.control(network, c); err != nil {
+			return err
+		}
+		switch {
+		case ctrlCtxFn != nil:
+			return ctrlCtxFn(ctx, network, address, c)
+		case ctrlFn != nil:
+			return ctrlFn(network, address, c)
+		}
+		return nil
+	}
+}
+
+// dialNet dials address with [net.Dialer], applying [Dialer.SocketOptions].
+func (d *Dialer) dialNet(ctx context.Context, network, address string) (net.Conn, error) {
+	nd := d.Dialer
+	nd.ControlContext = d.controlContext()
+	c, err := nd.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := c.(*net.TCPConn); ok && d.SocketOptions.DisableNoDelay {
+		if err = tc.SetNoDelay(false); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// control returns the Control function of [net.ListenConfig] that applies
+// [ListenConfig.SocketOptions] before calling the Control function of lc.
+func (lc *ListenConfig) control() func(string, string, syscall.RawConn) error {
+	if lc.SocketOptions.empty() {
+		return lc.Control
+	}
+	ctrlFn := lc.Control
+	return func(network, address string, c syscall.RawConn) error {
+		if err := lc.SocketOptions.control(network, c); err != nil {
+			return err
+		}
+		if ctrlFn != nil {
+			return ctrlFn(network, address, c)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package tfo
+
+import "golang.org/x/sys/unix"
+
+func setMark(fd uintptr, mark int) error {
+	return ErrUnsupported
+}
+
+func setBindToDevice(fd uintptr, name string) error {
+	return ErrUnsupported
+}
+
+func setUserTimeout(fd uintptr, msec int) error {
+	return ErrUnsupported
+}
+
+func setCongestionControl(fd uintptr, name string) error {
+	return unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION, name)
+}
+
+func setNotSentLowat(fd uintptr, lowat int) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,23 @@
+package tfo
+
+import "golang.org/x/sys/unix"
+
+func setMark(fd uintptr, mark int) error {
+	return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, mark)
+}
+
+func setBindToDevice(fd uintptr, name string) error {
+	return unix.BindToDevice(int(fd), name)
+}
+
+func setUserTimeout(fd uintptr, msec int) error {
+	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, msec)
+}
+
+func setCongestionControl(fd uintptr, name string) error {
+	return unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION, name)
+}
+
+func setNotSentLowat(fd uintptr, lowat int) error {
+	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_NOTSENT_LOWAT, lowat)
+}
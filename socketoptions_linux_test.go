@@ -0,0 +1,212 @@
+package tfo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var allSocketOptions = SocketOptions{
+	UserTimeout:       3 * time.Second,
+	CongestionControl: "reno",
+	TOS:               0x10,
+	SendBufferSize:    64 * 1024,
+	ReceiveBufferSize: 64 * 1024,
+	NotSentLowat:      16 * 1024,
+	Linger:            5 * time.Second,
+	DisableNoDelay:    true,
+}
+
+func checkSocketOptions(t *testing.T, c *net.TCPConn, want SocketOptions, wantNoDelay bool) {
+	t.Helper()
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		userTimeout, tclass, sndbuf, rcvbuf, lowat, noDelay int
+		congestion                                          string
+		linger                                              *unix.Linger
+	)
+	if cerr := rawConn.Control(func(fd uintptr) {
+		s := int(fd)
+		if userTimeout, err = unix.GetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT); err != nil {
+			return
+		}
+		if congestion, err = unix.GetsockoptString(s, unix.IPPROTO_TCP, unix.TCP_CONGESTION); err != nil {
+			return
+		}
+		if tclass, err = unix.GetsockoptInt(s, unix.IPPROTO_IPV6, unix.IPV6_TCLASS); err != nil {
+			return
+		}
+		if sndbuf, err = unix.GetsockoptInt(s, unix.SOL_SOCKET, unix.SO_SNDBUF); err != nil {
+			return
+		}
+		if rcvbuf, err = unix.GetsockoptInt(s, unix.SOL_SOCKET, unix.SO_RCVBUF); err != nil {
+			return
+		}
+		if lowat, err = unix.GetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_NOTSENT_LOWAT); err != nil {
+			return
+		}
+		if linger, err = unix.GetsockoptLinger(s, unix.SOL_SOCKET, unix.SO_LINGER); err != nil {
+			return
+		}
+		noDelay, err = unix.GetsockoptInt(s, unix.IPPROTO_TCP, unix.TCP_NODELAY)
+	}); cerr != nil {
+		t.Fatal(cerr)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Duration(userTimeout) * time.Millisecond; got != want.UserTimeout {
+		t.Errorf("TCP_USER_TIMEOUT = %v, want %v", got, want.UserTimeout)
+	}
+	if congestion != want.CongestionControl {
+		t.Errorf("TCP_CONGESTION = %q, want %q", congestion, want.CongestionControl)
+	}
+	if tclass != want.TOS {
+		t.Errorf("IPV6_TCLASS = %#x, want %#x", tclass, want.TOS)
+	}
+	// The kernel doubles the buffer sizes to make room for bookkeeping overhead.
+	if sndbuf != 2*want.SendBufferSize {
+		t.Errorf("SO_SNDBUF = %d, want %d", sndbuf, 2*want.SendBufferSize)
+	}
+	if rcvbuf != 2*want.ReceiveBufferSize {
+		t.Errorf("SO_RCVBUF = %d, want %d", rcvbuf, 2*want.ReceiveBufferSize)
+	}
+	if lowat != want.NotSentLowat {
+		t.Errorf("TCP_NOTSENT_LOWAT = %d, want %d", lowat, want.NotSentLowat)
+	}
+	if got := time.Duration(linger.Linger) * time.Second; linger.Onoff == 0 || got != want.Linger {
+		t.Errorf("SO_LINGER = {Onoff: %d, Linger: %v}, want {Onoff: 1, Linger: %v}", linger.Onoff, got, want.Linger)
+	}
+	if got := noDelay != 0; got != wantNoDelay {
+		t.Errorf("TCP_NODELAY = %v, want %v", got, wantNoDelay)
+	}
+}
+
+// TestSocketOptions ensures that [SocketOptions] is applied to dialed connections
+// on every dial path, and to accepted connections.
+func TestSocketOptions(t *testing.T) {
+	for _, c := range cases {
+		c.Run(t, testSocketOptions)
+	}
+}
+
+func testSocketOptions(t *testing.T, lc ListenConfig, d Dialer) {
+	lc.SocketOptions = allSocketOptions
+	d.SocketOptions = allSocketOptions
+
+	ln, err := lc.ListenTCP(context.Background(), "tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		for i := 0; i < 2; i++ {
+			conn, err := ln.AcceptTCP()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					t.Error(err)
+				}
+				return
+			}
+			// [net.TCPListener] sets TCP_NODELAY on accepted connections.
+			checkSocketOptions(t, conn, allSocketOptions, true)
+			readUntilEOF(conn, hello, t)
+			conn.Close()
+		}
+	}()
+	defer func() {
+		ln.Close()
+		<-ctrlCh
+	}()
+
+	for _, he := range []*HappyEyeballs{nil, {}} {
+		d.HappyEyeballs = he
+		c, err := d.Dial("tcp", ln.Addr().String(), hello)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSocketOptions(t, c.(*net.TCPConn), allSocketOptions, false)
+		c.(*net.TCPConn).CloseWrite()
+		c.Close()
+	}
+	<-ctrlCh
+}
+
+// TestSocketOptionsError ensures that failing to set an option fails
+// dial and listen calls with an error that names the option.
+func TestSocketOptionsError(t *testing.T) {
+	const name = "setsockopt(TCP_CONGESTION)"
+	so := SocketOptions{CongestionControl: "tfo-go-nonexistent"}
+
+	for _, c := range cases {
+		c.Run(t, func(t *testing.T, lc ListenConfig, d Dialer) {
+			ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			lc.SocketOptions = so
+			if ln, err := lc.Listen(context.Background(), "tcp", "[::1]:"); err == nil {
+				ln.Close()
+				t.Error("lc.Listen() succeeded, want error")
+			} else if sysErr := (*os.SyscallError)(nil); !errors.As(err, &sysErr) || sysErr.Syscall != name {
+				t.Errorf("lc.Listen() error = %v, want %s error", err, name)
+			}
+
+			d.SocketOptions = so
+			for _, he := range []*HappyEyeballs{nil, {}} {
+				d.HappyEyeballs = he
+				if c, err := d.Dial("tcp", ln.Addr().String(), hello); err == nil {
+					c.Close()
+					t.Error("d.Dial() succeeded, want error")
+				} else if sysErr := (*os.SyscallError)(nil); !errors.As(err, &sysErr) || sysErr.Syscall != name {
+					t.Errorf("d.Dial() error = %v, want %s error", err, name)
+				}
+			}
+		})
+	}
+}
+
+// TestSocketOptionsRounding ensures that short positive durations are rounded up,
+// instead of being truncated to values that mean something else.
+func TestSocketOptionsRounding(t *testing.T) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM, unix.IPPROTO_TCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fd)
+
+	so := SocketOptions{
+		UserTimeout: time.Microsecond,
+		Linger:      time.Millisecond,
+	}
+	if err = so.apply(uintptr(fd), false); err != nil {
+		t.Fatal(err)
+	}
+
+	userTimeout, err := unix.GetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userTimeout != 1 {
+		t.Errorf("TCP_USER_TIMEOUT = %dms, want 1ms", userTimeout)
+	}
+	linger, err := unix.GetsockoptLinger(fd, unix.SOL_SOCKET, unix.SO_LINGER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linger.Onoff != 1 || linger.Linger != 1 {
+		t.Errorf("SO_LINGER = %+v, want {Onoff:1 Linger:1}", *linger)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !darwin && !freebsd && !linux && !windows
+
+package tfo
+
+func setMark(fd uintptr, mark int) error {
+	return ErrUnsupported
+}
+
+func setBindToDevice(fd uintptr, name string) error {
+	return ErrUnsupported
+}
+
+func setUserTimeout(fd uintptr, msec int) error {
+	return ErrUnsupported
+}
+
+func setCongestionControl(fd uintptr, name string) error {
+	return ErrUnsupported
+}
+
+func setTOS(fd uintptr, ipv6 bool, tos int) error {
+	return ErrUnsupported
+}
+
+func setSendBuffer(fd uintptr, size int) error {
+	return ErrUnsupported
+}
+
+func setReceiveBuffer(fd uintptr, size int) error {
+	return ErrUnsupported
+}
+
+func setNotSentLowat(fd uintptr, lowat int) error {
+	return ErrUnsupported
+}
+
+func setLinger(fd uintptr, sec int) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,39 @@
+package tfo
+
+import "golang.org/x/sys/windows"
+
+func setMark(fd uintptr, mark int) error {
+	return ErrUnsupported
+}
+
+func setBindToDevice(fd uintptr, name string) error {
+	return ErrUnsupported
+}
+
+func setUserTimeout(fd uintptr, msec int) error {
+	return ErrUnsupported
+}
+
+func setCongestionControl(fd uintptr, name string) error {
+	return ErrUnsupported
+}
+
+func setTOS(fd uintptr, ipv6 bool, tos int) error {
+	return ErrUnsupported
+}
+
+func setSendBuffer(fd uintptr, size int) error {
+	return windows.SetsockoptInt(windows.Handle(fd), windows.SOL_SOCKET, windows.SO_SNDBUF, size)
+}
+
+func setReceiveBuffer(fd uintptr, size int) error {
+	return windows.SetsockoptInt(windows.Handle(fd), windows.SOL_SOCKET, windows.SO_RCVBUF, size)
+}
+
+func setNotSentLowat(fd uintptr, lowat int) error {
+	return ErrUnsupported
+}
+
+func setLinger(fd uintptr, sec int) error {
+	return windows.SetsockoptLinger(windows.Handle(fd), windows.SOL_SOCKET, windows.SO_LINGER, &windows.Linger{Onoff: 1, Linger: int32(sec)})
+}
@@ -18,3 +18,29 @@ func SetTFOListenerWithBacklog(fd uintptr, backlog int) error {
 func SetTFODialer(fd uintptr) error {
 	return setTFODialer(fd) // sockopt_darwin.go, sockopt_linux.go, sockopt_connect_generic.go, sockopt_stub.go
 }
+
+// SetTFONoCookie makes the socket send or accept data in SYN without a TFO cookie.
+// On the client, data is sent in SYN without a cookie option.
+// On the listener, data in SYN is accepted without a valid cookie.
+//
+// This is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func SetTFONoCookie(fd uintptr) error {
+	return setTFONoCookie(fd) // sockopt_linux.go, sockopt_linux_stub.go
+}
+
+// SetTFOKeys sets the TFO cookie keys of the listener.
+// The first key is the primary key, which is used to generate and validate cookies.
+// The optional second key is the backup key, which is only used to validate cookies.
+//
+// This is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func SetTFOKeys(fd uintptr, keys ...TFOKey) error {
+	return setTFOKeys(fd, keys) // sockopt_linux.go, sockopt_linux_stub.go
+}
+
+// GetTFOKeys returns the TFO cookie keys of the listener, primary key first.
+// If the listener has no keys of its own, the keys of its network namespace are returned.
+//
+// This is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func GetTFOKeys(fd uintptr) ([]TFOKey, error) {
+	return getTFOKeys(fd) // sockopt_linux.go, sockopt_linux_stub.go
+}
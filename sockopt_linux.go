@@ -1,7 +1,7 @@
 package tfo
 
 import (
-	_ "unsafe"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
@@ -32,3 +32,35 @@ func listenerBacklog() int
 func setTFODialer(fd uintptr) error {
 	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
 }
+
+func setTFONoCookie(fd uintptr) error {
+	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_NO_COOKIE, 1)
+}
+
+func setTFOKeys(fd uintptr, keys []TFOKey) error {
+	if len(keys) == 0 {
+		return errNoTFOKeys
+	}
+	if len(keys) > 2 {
+		return errTooManyTFOKeys
+	}
+	var buf [2 * TFOKeyLen]byte
+	for i, key := range keys {
+		copy(buf[i*TFOKeyLen:], key[:])
+	}
+	return unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_KEY, string(buf[:len(keys)*TFOKeyLen]))
+}
+
+func getTFOKeys(fd uintptr) ([]TFOKey, error) {
+	var buf [2 * TFOKeyLen]byte
+	n := uint32(len(buf))
+	_, _, e1 := unix.Syscall6(unix.SYS_GETSOCKOPT, fd, unix.IPPROTO_TCP, unix.TCP_FASTOPEN_KEY, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&n)), 0)
+	if e1 != 0 {
+		return nil, e1
+	}
+	keys := make([]TFOKey, n/TFOKeyLen)
+	for i := range keys {
+		copy(keys[i][:], buf[i*TFOKeyLen:])
+	}
+	return keys, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package tfo
+
+func setTFOKeys(fd uintptr, keys []TFOKey) error {
+	return ErrUnsupported
+}
+
+func getTFOKeys(fd uintptr) ([]TFOKey, error) {
+	return nil, ErrUnsupported
+}
+
+func setTFONoCookie(fd uintptr) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,200 @@
+package tfo
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Stats collects counters about TFO dial and listen calls.
+//
+// Set [Dialer.Stats] and [ListenConfig.Stats] to the same Stats to share it.
+// A Stats is safe for concurrent use. The zero value is ready to use.
+type Stats struct {
+	dialAttempts        atomic.Uint64
+	dialSuccesses       atomic.Uint64
+	dialFallbacks       atomic.Uint64
+	dialNoTFO           atomic.Uint64
+	dialErrors          atomic.Uint64
+	fallbacks           [FallbackReasonBlackhole + 1]atomic.Uint64
+	linuxSendtoSwitches atomic.Uint64
+	runtimeDisables     atomic.Uint64
+	synDataBytes        atomic.Uint64
+	listenAttempts      atomic.Uint64
+	listenFailures      atomic.Uint64
+
+	errnoMu sync.Mutex
+	errnos  map[syscall.Errno]uint64
+
+	traceOnce sync.Once
+	trace     *DialTrace
+}
+
+// StatsSnapshot is a copy of the counters in a [Stats].
+type StatsSnapshot struct {
+	// DialAttempts is the number of dial calls that attempted TFO.
+	// Each of them is counted in exactly one of DialSuccesses, DialFallbacks,
+	// DialNoTFO and DialErrors.
+	DialAttempts uint64
+
+	// DialSuccesses is the number of dial calls that established a connection with TFO.
+	DialSuccesses uint64
+
+	// DialFallbacks is the number of dial calls that established a connection without TFO
+	// after falling back. See [DialMethodFallback].
+	DialFallbacks uint64
+
+	// DialNoTFO is the number of dial calls that established a connection without TFO
+	// and without falling back, e.g. because another connection attempt was carrying
+	// the data in SYN. See [DialMethodNoTFO].
+	DialNoTFO uint64
+
+	// DialErrors is the number of dial calls that failed.
+	DialErrors uint64
+
+	// FallbackUnsupported, FallbackDisabled, FallbackUnhealthy and FallbackBlackhole
+	// are the number of fallback decisions made for each [FallbackReason].
+	// A dial call may make more than one decision, one for each address it tries.
+	FallbackUnsupported uint64
+	FallbackDisabled    uint64
+	FallbackUnhealthy   uint64
+	FallbackBlackhole   uint64
+
+	// LinuxSendtoSwitches is the number of times TCP_FASTOPEN_CONNECT was found unsupported
+	// on Linux, and dial calls switched to sendto(MSG_FASTOPEN).
+	LinuxSendtoSwitches uint64
+
+	// RuntimeDisables is the number of times TFO was disabled for the whole process
+	// after a dial call found it unsupported.
+	RuntimeDisables uint64
+
+	// SYNDataBytes is the number of bytes handed to the kernel along with connection requests.
+	SYNDataBytes uint64
+
+	// ListenAttempts is the number of listen calls that attempted TFO.
+	ListenAttempts uint64
+
+	// ListenFailures is the number of listen calls that failed to enable TFO.
+	ListenFailures uint64
+
+	// Errnos is the number of errors by errno, from failed dial calls and
+	// failures to enable TFO on listeners.
+	Errnos map[string]uint64
+}
+
+// Snapshot returns a copy of the counters.
+func (s *Stats) Snapshot() StatsSnapshot {
+	snap := StatsSnapshot{
+		DialAttempts:        s.dialAttempts.Load(),
+		DialSuccesses:       s.dialSuccesses.Load(),
+		DialFallbacks:       s.dialFallbacks.Load(),
+		DialNoTFO:           s.dialNoTFO.Load(),
+		DialErrors:          s.dialErrors.Load(),
+		FallbackUnsupported: s.fallbacks[FallbackReasonUnsupported].Load(),
+		FallbackDisabled:    s.fallbacks[FallbackReasonDisabled].Load(),
+		FallbackUnhealthy:   s.fallbacks[FallbackReasonUnhealthy].Load(),
+		FallbackBlackhole:   s.fallbacks[FallbackReasonBlackhole].Load(),
+		LinuxSendtoSwitches: s.linuxSendtoSwitches.Load(),
+		RuntimeDisables:     s.runtimeDisables.Load(),
+		SYNDataBytes:        s.synDataBytes.Load(),
+		ListenAttempts:      s.listenAttempts.Load(),
+		ListenFailures:      s.listenFailures.Load(),
+		Errnos:              make(map[string]uint64),
+	}
+	s.errnoMu.Lock()
+	for errno, n := range s.errnos {
+		snap.Errnos[errno.Error()] += n
+	}
+	s.errnoMu.Unlock()
+	return snap
+}
+
+// String returns a snapshot of the counters in JSON.
+// It makes a *Stats an expvar.Var, so it can be published with expvar.Publish.
+func (s *Stats) String() string {
+	b, err := json.Marshal(s.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// dialTrace returns the trace hooks that count fallback decisions.
+func (s *Stats) dialTrace() *DialTrace {
+	s.traceOnce.Do(func() {
+		s.trace = &DialTrace{
+			Fallback: func(_, _ string, reason FallbackReason, _ error) {
+				if int(reason) < len(s.fallbacks) {
+					s.fallbacks[reason].Add(1)
+				}
+			},
+		}
+	})
+	return s.trace
+}
+
+func (s *Stats) addErrno(err error) {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return
+	}
+	s.errnoMu.Lock()
+	if s.errnos == nil {
+		s.errnos = make(map[syscall.Errno]uint64)
+	}
+	s.errnos[errno]++
+	s.errnoMu.Unlock()
+}
+
+func (s *Stats) dialStart() {
+	if s != nil {
+		s.dialAttempts.Add(1)
+	}
+}
+
+func (s *Stats) dialDone(info *DialInfo, err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.dialErrors.Add(1)
+		s.addErrno(err)
+		return
+	}
+	switch info.Method {
+	case DialMethodFastOpenConnect, DialMethodSendmsg:
+		s.dialSuccesses.Add(1)
+	case DialMethodFallback:
+		s.dialFallbacks.Add(1)
+	case DialMethodNoTFO:
+		s.dialNoTFO.Add(1)
+	}
+	s.synDataBytes.Add(uint64(info.SYNDataLen))
+}
+
+func (s *Stats) linuxSendtoSwitch() {
+	if s != nil {
+		s.linuxSendtoSwitches.Add(1)
+	}
+}
+
+func (s *Stats) runtimeDisable() {
+	if s != nil {
+		s.runtimeDisables.Add(1)
+	}
+}
+
+func (s *Stats) listenStart() {
+	if s != nil {
+		s.listenAttempts.Add(1)
+	}
+}
+
+func (s *Stats) listenFailure(err error) {
+	if s != nil {
+		s.listenFailures.Add(1)
+		s.addErrno(err)
+	}
+}
@@ -0,0 +1,94 @@
+package tfo
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+// TestStats ensures that [Stats] counts dial and listen calls.
+func TestStats(t *testing.T) {
+	if comptimeDialNoTFO || comptimeListenNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	var stats Stats
+
+	lc := ListenConfig{Stats: &stats}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ap := ln.Addr().(*net.TCPAddr).AddrPort()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		for i := 0; i < 2; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			readUntilEOF(conn, hello, t)
+			conn.Close()
+		}
+	}()
+
+	var h HealthCache
+	d := Dialer{Fallback: true, HealthCache: &h, Stats: &stats}
+
+	for i := 0; i < 2; i++ {
+		c, err := d.Dial("tcp", address, hello)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.(*net.TCPConn).CloseWrite()
+		h.MarkFailed(ap)
+		defer c.Close()
+	}
+	<-ctrlCh
+	ln.Close()
+
+	if _, err = d.Dial("tcp", address, hello); err == nil {
+		t.Fatal("Dial to a closed listener succeeded")
+	}
+
+	snap := stats.Snapshot()
+	t.Logf("snapshot: %+v", snap)
+
+	if snap.ListenAttempts != 1 {
+		t.Errorf("ListenAttempts = %d, want 1", snap.ListenAttempts)
+	}
+	if snap.DialAttempts != 3 {
+		t.Errorf("DialAttempts = %d, want 3", snap.DialAttempts)
+	}
+	if n := snap.DialSuccesses + snap.DialFallbacks + snap.DialNoTFO; n != 2 {
+		t.Errorf("DialSuccesses + DialFallbacks + DialNoTFO = %d, want 2", n)
+	}
+	if n := snap.DialSuccesses + snap.DialFallbacks + snap.DialNoTFO + snap.DialErrors; n != snap.DialAttempts {
+		t.Errorf("dial outcomes add up to %d, want DialAttempts = %d", n, snap.DialAttempts)
+	}
+	if snap.DialFallbacks == 0 || snap.FallbackUnhealthy == 0 {
+		t.Errorf("DialFallbacks = %d, FallbackUnhealthy = %d, want non-zero", snap.DialFallbacks, snap.FallbackUnhealthy)
+	}
+	if snap.DialErrors != 1 {
+		t.Errorf("DialErrors = %d, want 1", snap.DialErrors)
+	}
+	if len(snap.Errnos) != 1 {
+		t.Errorf("Errnos = %v, want the errno of the refused connection", snap.Errnos)
+	}
+	if snap.DialSuccesses > 0 && snap.SYNDataBytes > uint64(len(hello)) {
+		t.Errorf("SYNDataBytes = %d, want at most %d", snap.SYNDataBytes, len(hello))
+	}
+
+	var v StatsSnapshot
+	if err = json.Unmarshal([]byte(stats.String()), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.DialAttempts != snap.DialAttempts {
+		t.Errorf("JSON DialAttempts = %d, want %d", v.DialAttempts, snap.DialAttempts)
+	}
+}
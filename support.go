@@ -0,0 +1,108 @@
+package tfo
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DialSupport is the TFO support for dial calls, as detected at runtime by dial calls
+// with [Dialer.Fallback] set to true.
+type DialSupport uint8
+
+const (
+	// DialSupportDefault means no lack of support has been detected,
+	// and the platform's preferred mechanism is used.
+	DialSupportDefault DialSupport = DialSupport(dialTFOSupportDefault)
+
+	// DialSupportNone means TFO was found unsupported, and dial calls with
+	// [Dialer.Fallback] set to true proceed without TFO.
+	DialSupportNone DialSupport = DialSupport(dialTFOSupportNone)
+
+	// DialSupportLinuxSendto means TCP_FASTOPEN_CONNECT was found unsupported on Linux,
+	// and dial calls use sendto(MSG_FASTOPEN) instead.
+	DialSupportLinuxSendto DialSupport = DialSupport(dialTFOSupportLinuxSendto)
+)
+
+// String returns the string representation of the dial support.
+func (s DialSupport) String() string {
+	switch s {
+	case DialSupportDefault:
+		return "default"
+	case DialSupportNone:
+		return "none"
+	case DialSupportLinuxSendto:
+		return "Linux sendto"
+	default:
+		return "DialSupport(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// RuntimeSupport is the TFO support detected at runtime by dial and listen calls
+// with Fallback set to true. Once lack of support is detected, it applies to the whole process
+// until [ResetRuntimeSupport] is called.
+type RuntimeSupport struct {
+	// Dial is the detected dial support.
+	Dial DialSupport
+
+	// DialBlackholed reports whether TFO is disabled for dial calls after a blackhole
+	// was detected. See [Dialer.SYNDataTimeout].
+	DialBlackholed bool
+
+	// ListenUnsupported reports whether a listen call found TFO unsupported, so that
+	// listen calls with [ListenConfig.Fallback] set to true proceed without TFO.
+	ListenUnsupported bool
+}
+
+// GetRuntimeSupport returns the TFO support detected at runtime.
+func GetRuntimeSupport() RuntimeSupport {
+	return RuntimeSupport{
+		Dial:              DialSupport(runtimeDialTFOSupport.load()),
+		DialBlackholed:    runtimeDialTFOBlackhole.active(time.Now()),
+		ListenUnsupported: runtimeListenNoTFO.Load(),
+	}
+}
+
+// ResetRuntimeSupport forgets the TFO support detected at runtime,
+// so that the next dial and listen calls detect it again.
+// This is useful after TFO has been enabled on the system, e.g. with [WriteSysctl].
+//
+// A detected blackhole is not forgotten, as it is not about support on the system.
+// It expires on its own, see [Dialer.SYNDataTimeout].
+func ResetRuntimeSupport() {
+	runtimeDialTFOSupport.reset()
+	runtimeListenNoTFO.Store(false)
+}
+
+// StartRuntimeSupportReprobe calls [ResetRuntimeSupport] every interval
+// if lack of support has been detected, so that a long-running process picks up
+// TFO once it is enabled on the system. The next dial and listen calls after each reset
+// act as the probe, and detect the lack of support again if it persists.
+//
+// Call the returned function to stop re-probing.
+func StartRuntimeSupportReprobe(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		panic("non-positive interval for StartRuntimeSupportReprobe")
+	}
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if s := GetRuntimeSupport(); s.Dial != DialSupportDefault || s.ListenUnsupported {
+					ResetRuntimeSupport()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package tfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuntimeSupport(t *testing.T) {
+	if s := GetRuntimeSupport(); s != (RuntimeSupport{}) {
+		t.Fatalf("GetRuntimeSupport() = %+v, want zero value", s)
+	}
+
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+		want               RuntimeSupport
+		wantAfterReset     RuntimeSupport
+	}{
+		{"DialNoTFO", runtimeFallbackSetDialNoTFO, RuntimeSupport{Dial: DialSupportNone}, RuntimeSupport{}},
+		{"DialLinuxSendto", runtimeFallbackSetDialLinuxSendto, RuntimeSupport{Dial: DialSupportLinuxSendto}, RuntimeSupport{}},
+		{"DialBlackhole", runtimeFallbackSetDialBlackhole, RuntimeSupport{DialBlackholed: true}, RuntimeSupport{DialBlackholed: true}},
+		{"ListenNoTFO", runtimeFallbackSetListenNoTFO, RuntimeSupport{ListenUnsupported: true}, RuntimeSupport{}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+			if s := GetRuntimeSupport(); s != c.want {
+				t.Errorf("GetRuntimeSupport() = %+v, want %+v", s, c.want)
+			}
+			ResetRuntimeSupport()
+			if s := GetRuntimeSupport(); s != c.wantAfterReset {
+				t.Errorf("GetRuntimeSupport() after reset = %+v, want %+v", s, c.wantAfterReset)
+			}
+		})
+	}
+}
+
+func TestStartRuntimeSupportReprobe(t *testing.T) {
+	runtimeFallbackSetDialNoTFO(t)
+	runtimeFallbackSetListenNoTFO(t)
+	runtimeFallbackSetDialBlackhole(t)
+
+	stop := StartRuntimeSupportReprobe(time.Millisecond)
+	defer stop()
+
+	// Re-probing must not cut the blackhole backoff short.
+	deadline := time.Now().Add(5 * time.Second)
+	for GetRuntimeSupport() != (RuntimeSupport{DialBlackholed: true}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("runtime support was not reset: %+v", GetRuntimeSupport())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stop()
+	stop()
+}
+
+func TestDialSupportString(t *testing.T) {
+	for _, c := range []struct {
+		s    DialSupport
+		want string
+	}{
+		{DialSupportDefault, "default"},
+		{DialSupportNone, "none"},
+		{DialSupportLinuxSendto, "Linux sendto"},
+		{DialSupport(42), "DialSupport(42)"},
+	} {
+		if got := c.s.String(); got != c.want {
+			t.Errorf("%d.String() = %q, want %q", c.s, got, c.want)
+		}
+	}
+}
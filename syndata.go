@@ -0,0 +1,106 @@
+package tfo
+
+import "sync"
+
+// MaxSYNDataMSS is a special value for [Dialer.MaxSYNData] that caps data in SYN
+// to the default MSS from RFC 7413, so that it fits in the SYN even when the MSS
+// of the path is not known yet.
+const MaxSYNDataMSS = -1
+
+const (
+	defaultIPv4MSS = 536
+	defaultIPv6MSS = 1220
+)
+
+// synDataLimit returns the number of bytes of bufs that may be sent in SYN
+// according to [Dialer.ReplaySafeLen] and a positive [Dialer.MaxSYNData],
+// or -1 if there is no such limit.
+func (d *Dialer) synDataLimit(bufs [][]byte) int {
+	limit := -1
+	if d.ReplaySafeLen != nil {
+		limit = d.ReplaySafeLen(bufs)
+		if limit < 0 {
+			limit = 0
+		}
+	}
+	if d.MaxSYNData > 0 && (limit < 0 || d.MaxSYNData < limit) {
+		limit = d.MaxSYNData
+	}
+	if limit >= buffersLen(bufs) {
+		return -1
+	}
+	return limit
+}
+
+// synData returns the prefix of bufs to send in SYN to an IPv4 or IPv6 destination.
+// limit, if positive, is the limit returned by [Dialer.synDataLimit].
+func (d *Dialer) synData(bufs [][]byte, limit int, ipv6 bool) [][]byte {
+	if limit <= 0 {
+		limit = -1
+	}
+	if d.MaxSYNData == MaxSYNDataMSS {
+		if mss := defaultMSS(ipv6); limit < 0 || mss < limit {
+			limit = mss
+		}
+	}
+	if limit < 0 || limit >= buffersLen(bufs) {
+		return bufs
+	}
+	return prefixBuffers(bufs, limit)
+}
+
+// defaultMSS returns the default MSS from RFC 7413 for an IPv4 or IPv6 destination.
+func defaultMSS(ipv6 bool) int {
+	if ipv6 {
+		return defaultIPv6MSS
+	}
+	return defaultIPv4MSS
+}
+
+// prefixBuffers returns the first n bytes of bufs. bufs is not modified.
+func prefixBuffers(bufs [][]byte, n int) [][]byte {
+	var prefix [][]byte
+	for _, b := range bufs {
+		if n <= 0 {
+			break
+		}
+		if len(b) > n {
+			b = b[:n]
+		}
+		prefix = append(prefix, b)
+		n -= len(b)
+	}
+	return prefix
+}
+
+// synDataRace hands the data in SYN to one connection attempt at a time,
+// for [Dialer.SingleSYNDataRacer]. A nil *synDataRace lets every attempt carry it.
+type synDataRace struct {
+	mu   sync.Mutex
+	held bool
+}
+
+// acquire reports whether the caller may carry the data in SYN.
+// If it returns true, the caller must call release if its attempt fails.
+func (r *synDataRace) acquire() bool {
+	if r == nil {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.held {
+		return false
+	}
+	r.held = true
+	return true
+}
+
+// release lets another attempt carry the data in SYN.
+func (r *synDataRace) release() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.held = false
+	r.mu.Unlock()
+}
@@ -0,0 +1,153 @@
+package tfo
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+)
+
+func TestSYNData(t *testing.T) {
+	bufs := [][]byte{[]byte("hel"), []byte("lo"), []byte("world")}
+	for _, c := range []struct {
+		name        string
+		dialer      Dialer
+		ipv6        bool
+		wantLimit   int
+		wantSYNData string
+	}{
+		{
+			name:        "NoLimit",
+			dialer:      Dialer{},
+			wantLimit:   -1,
+			wantSYNData: "helloworld",
+		},
+		{
+			name:        "MaxSYNData",
+			dialer:      Dialer{MaxSYNData: 4},
+			wantLimit:   4,
+			wantSYNData: "hell",
+		},
+		{
+			name:        "MaxSYNDataAboveLen",
+			dialer:      Dialer{MaxSYNData: 100},
+			wantLimit:   -1,
+			wantSYNData: "helloworld",
+		},
+		{
+			name:        "MaxSYNDataMSS",
+			dialer:      Dialer{MaxSYNData: MaxSYNDataMSS},
+			wantLimit:   -1,
+			wantSYNData: "helloworld",
+		},
+		{
+			name: "ReplaySafeLen",
+			dialer: Dialer{ReplaySafeLen: func(b net.Buffers) int {
+				return 5
+			}},
+			wantLimit:   5,
+			wantSYNData: "hello",
+		},
+		{
+			name: "ReplaySafeLenZero",
+			dialer: Dialer{ReplaySafeLen: func(b net.Buffers) int {
+				return 0
+			}},
+			wantLimit: 0,
+		},
+		{
+			name: "ReplaySafeLenAndMaxSYNData",
+			dialer: Dialer{MaxSYNData: 2, ReplaySafeLen: func(b net.Buffers) int {
+				return 5
+			}},
+			wantLimit:   2,
+			wantSYNData: "he",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			limit := c.dialer.synDataLimit(bufs)
+			if limit != c.wantLimit {
+				t.Errorf("synDataLimit() = %d, want %d", limit, c.wantLimit)
+			}
+			if limit == 0 {
+				return // dial calls do not use TFO
+			}
+			if got := string(bytes.Join(c.dialer.synData(bufs, limit, c.ipv6), nil)); got != c.wantSYNData {
+				t.Errorf("synData() = %q, want %q", got, c.wantSYNData)
+			}
+		})
+	}
+}
+
+func TestSYNDataMSS(t *testing.T) {
+	b := make([]byte, 2000)
+	d := Dialer{MaxSYNData: MaxSYNDataMSS}
+	for _, c := range []struct {
+		ipv6 bool
+		want int
+	}{
+		{false, defaultIPv4MSS},
+		{true, defaultIPv6MSS},
+	} {
+		if got := buffersLen(d.synData([][]byte{b}, 0, c.ipv6)); got != c.want {
+			t.Errorf("ipv6 = %v: synData() has %d bytes, want %d", c.ipv6, got, c.want)
+		}
+	}
+}
+
+// TestDialMaxSYNData ensures that dial calls with a limit on data in SYN
+// hand no more than the limit to the kernel along with the connection request,
+// and still deliver all of the data.
+func TestDialMaxSYNData(t *testing.T) {
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+			for _, d := range []Dialer{
+				{MaxSYNData: 3},
+				{ReplaySafeLen: func(b net.Buffers) int { return 3 }},
+				{ReplaySafeLen: func(b net.Buffers) int { return 0 }},
+			} {
+				d.Dialer = c.dialer.Dialer
+				d.DisableTFO = c.dialer.DisableTFO
+				d.Fallback = c.dialer.Fallback
+				testDialMaxSYNData(t, d)
+			}
+		})
+	}
+}
+
+func testDialMaxSYNData(t *testing.T, d Dialer) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, helloWorldSentence, t)
+	}()
+
+	c, info, err := d.DialContextInfo(context.Background(), "tcp", ln.Addr().String(), helloWorldSentence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.(*net.TCPConn).CloseWrite()
+	<-ctrlCh
+
+	if info.SYNDataLen > 3 {
+		t.Errorf("info.SYNDataLen = %d, want at most 3", info.SYNDataLen)
+	}
+	if d.ReplaySafeLen != nil && d.ReplaySafeLen(nil) == 0 && info.Method != DialMethodNoTFO {
+		t.Errorf("info.Method = %v, want %v", info.Method, DialMethodNoTFO)
+	}
+}
@@ -0,0 +1,100 @@
+package tfo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SysctlFlags is the bitmask of the net.ipv4.tcp_fastopen sysctl on Linux.
+type SysctlFlags uint32
+
+const (
+	// SysctlClientEnable enables sending data in the opening SYN on the client.
+	SysctlClientEnable SysctlFlags = 0x1
+
+	// SysctlServerEnable enables support for TFO on the server.
+	SysctlServerEnable SysctlFlags = 0x2
+
+	// SysctlClientNoCookie makes the client send data in the opening SYN
+	// regardless of cookie availability and without a cookie option.
+	SysctlClientNoCookie SysctlFlags = 0x4
+
+	// SysctlServerNoCookie makes the server accept data in SYN without any cookie option.
+	SysctlServerNoCookie SysctlFlags = 0x200
+
+	// SysctlServerForce enables TFO on all listeners, without requiring the TCP_FASTOPEN socket option.
+	SysctlServerForce SysctlFlags = 0x400
+)
+
+// DefaultSysctlFlags is the default value of the sysctl.
+const DefaultSysctlFlags = SysctlClientEnable
+
+var sysctlFlagNames = [...]struct {
+	flag SysctlFlags
+	name string
+}{
+	{SysctlClientEnable, "client"},
+	{SysctlServerEnable, "server"},
+	{SysctlClientNoCookie, "client-no-cookie"},
+	{SysctlServerNoCookie, "server-no-cookie"},
+	{SysctlServerForce, "server-force"},
+}
+
+// Has returns whether all bits of flags are set in f.
+func (f SysctlFlags) Has(flags SysctlFlags) bool {
+	return f&flags == flags
+}
+
+// String returns the names of the bits set in f, separated by "|".
+// Unknown bits are printed in hex.
+func (f SysctlFlags) String() string {
+	if f == 0 {
+		return "0"
+	}
+	var names []string
+	for _, n := range sysctlFlagNames {
+		if f&n.flag != 0 {
+			names = append(names, n.name)
+			f &^= n.flag
+		}
+	}
+	if f != 0 {
+		names = append(names, "0x"+strconv.FormatUint(uint64(f), 16))
+	}
+	return strings.Join(names, "|")
+}
+
+// ReadSysctl reads the net.ipv4.tcp_fastopen sysctl of the current network namespace
+// from the procfs mounted at procRoot. If procRoot is empty, "/proc" is used.
+//
+// This is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func ReadSysctl(procRoot string) (SysctlFlags, error) {
+	return readSysctl(procRoot) // sysctl_linux.go, sysctl_stub.go
+}
+
+// WriteSysctl writes flags to the net.ipv4.tcp_fastopen sysctl of the current network namespace
+// through the procfs mounted at procRoot. If procRoot is empty, "/proc" is used.
+//
+// Writing the sysctl requires privileges. Without them, an error that matches
+// [io/fs.ErrPermission] is returned.
+//
+// This is only supported on Linux. On other platforms, [ErrUnsupported] is returned.
+func WriteSysctl(procRoot string, flags SysctlFlags) error {
+	return writeSysctl(procRoot, flags) // sysctl_linux.go, sysctl_stub.go
+}
+
+// EnsureSysctl sets the bits of flags in the net.ipv4.tcp_fastopen sysctl that are not already set,
+// leaving the other bits unchanged. The sysctl is only written when a bit is missing.
+// It returns the value the sysctl had before the call.
+//
+// See [ReadSysctl] and [WriteSysctl] for details.
+func EnsureSysctl(procRoot string, flags SysctlFlags) (old SysctlFlags, err error) {
+	old, err = ReadSysctl(procRoot)
+	if err != nil {
+		return 0, err
+	}
+	if old.Has(flags) {
+		return old, nil
+	}
+	return old, WriteSysctl(procRoot, old|flags)
+}
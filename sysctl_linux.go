@@ -0,0 +1,42 @@
+package tfo
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const sysctlPath = "sys/net/ipv4/tcp_fastopen"
+
+func sysctlFilePath(procRoot string) string {
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	return filepath.Join(procRoot, sysctlPath)
+}
+
+func readSysctl(procRoot string) (SysctlFlags, error) {
+	path := sysctlFilePath(procRoot)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 32)
+	if err != nil {
+		return 0, &os.PathError{Op: "parse", Path: path, Err: err}
+	}
+	return SysctlFlags(v), nil
+}
+
+func writeSysctl(procRoot string, flags SysctlFlags) error {
+	f, err := os.OpenFile(sysctlFilePath(procRoot), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strconv.FormatUint(uint64(flags), 10) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
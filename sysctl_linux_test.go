@@ -0,0 +1,89 @@
+package tfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestProcRoot(t *testing.T, value string) string {
+	t.Helper()
+	procRoot := t.TempDir()
+	path := filepath.Join(procRoot, sysctlPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(value), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return procRoot
+}
+
+func TestReadSysctl(t *testing.T) {
+	for _, c := range []struct {
+		value   string
+		want    SysctlFlags
+		wantErr bool
+	}{
+		{"1\n", SysctlClientEnable, false},
+		{"3\n", SysctlClientEnable | SysctlServerEnable, false},
+		{"1543\n", SysctlClientEnable | SysctlServerEnable | SysctlClientNoCookie | SysctlServerNoCookie | SysctlServerForce, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"0x3\n", 0, true},
+	} {
+		procRoot := newTestProcRoot(t, c.value)
+		got, err := ReadSysctl(procRoot)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ReadSysctl() with %q returned error %v, wantErr %v", c.value, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ReadSysctl() with %q = %v, want %v", c.value, got, c.want)
+		}
+	}
+}
+
+func TestEnsureSysctl(t *testing.T) {
+	procRoot := newTestProcRoot(t, "1\n")
+
+	old, err := EnsureSysctl(procRoot, SysctlServerEnable|SysctlServerForce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != SysctlClientEnable {
+		t.Errorf("old = %v, want %v", old, SysctlClientEnable)
+	}
+
+	got, err := ReadSysctl(procRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := SysctlClientEnable | SysctlServerEnable | SysctlServerForce; got != want {
+		t.Errorf("ReadSysctl() = %v, want %v", got, want)
+	}
+
+	b, err := os.ReadFile(filepath.Join(procRoot, sysctlPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "1027\n" {
+		t.Errorf("sysctl file content = %q, want %q", b, "1027\n")
+	}
+}
+
+func TestSysctlFlagsString(t *testing.T) {
+	for _, c := range []struct {
+		flags SysctlFlags
+		want  string
+	}{
+		{0, "0"},
+		{SysctlClientEnable, "client"},
+		{SysctlClientEnable | SysctlServerEnable, "client|server"},
+		{SysctlServerForce | 0x1000, "server-force|0x1000"},
+	} {
+		if got := c.flags.String(); got != c.want {
+			t.Errorf("SysctlFlags(%#x).String() = %q, want %q", uint32(c.flags), got, c.want)
+		}
+	}
+}
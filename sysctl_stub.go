@@ -0,0 +1,11 @@
+//go:build !linux
+
+package tfo
+
+func readSysctl(procRoot string) (SysctlFlags, error) {
+	return 0, ErrUnsupported
+}
+
+func writeSysctl(procRoot string, flags SysctlFlags) error {
+	return ErrUnsupported
+}
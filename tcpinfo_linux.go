@@ -0,0 +1,72 @@
+package tfo
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/cpu"
+	"golang.org/x/sys/unix"
+)
+
+// TCP states from include/net/tcp_states.h.
+const (
+	tcpSynSent   = 2
+	tcpSynRecv   = 3
+	tcpCloseWait = 8
+)
+
+// comptimeNoTFOInfo is whether [ConnTFOInfo] is not supported on the current platform.
+const comptimeNoTFOInfo = false
+
+// tcpiOptSYNData is TCPI_OPT_SYN_DATA, which is set in tcpi_options when data in SYN was acknowledged.
+const tcpiOptSYNData = 32
+
+func getTCPInfo(fd uintptr) (*unix.TCPInfo, error) {
+	return unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+}
+
+// tcpInfoClientFail extracts tcpi_fastopen_client_fail from struct tcp_info.
+//
+// [unix.TCPInfo] does not have the bitfield byte that follows tcpi_wscale,
+// but the kernel still writes it into the padding before tcpi_rto.
+func tcpInfoClientFail(ti *unix.TCPInfo) TFOClientFail {
+	b := (*[8]byte)(unsafe.Pointer(ti))[7]
+	// __u8 tcpi_delivery_rate_app_limited:1, tcpi_fastopen_client_fail:2;
+	if cpu.IsBigEndian {
+		return TFOClientFail(b >> 5 & 3)
+	}
+	return TFOClientFail(b >> 1 & 3)
+}
+
+func getTFOInfo(fd uintptr) (TFOInfo, error) {
+	ti, err := getTCPInfo(fd)
+	if err != nil {
+		return TFOInfo{}, wrapSyscallError("getsockopt(TCP_INFO)", err)
+	}
+	return TFOInfo{
+		Established:  ti.State != tcpSynSent && ti.State != tcpSynRecv,
+		SYNDataAcked: ti.Options&tcpiOptSYNData != 0,
+		ClientFail:   tcpInfoClientFail(ti),
+	}, nil
+}
+
+func getAcceptInfo(fd uintptr) (AcceptInfo, error) {
+	ti, err := getTCPInfo(fd)
+	if err != nil {
+		return AcceptInfo{}, wrapSyscallError("getsockopt(TCP_INFO)", err)
+	}
+	if ti.Options&tcpiOptSYNData == 0 {
+		return AcceptInfo{}, nil
+	}
+	// The kernel sets tcpi_bytes_received to the length of the SYN data
+	// when it creates the child socket. Data that arrives before accept,
+	// after the handshake completes, also counts towards it, so this is
+	// only an upper bound. So does a FIN, which is easy to discount.
+	n := int(ti.Bytes_received)
+	if ti.State == tcpCloseWait && n > 0 {
+		n--
+	}
+	return AcceptInfo{
+		TFO:        true,
+		SYNDataLen: n,
+	}, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package tfo
+
+// comptimeNoTFOInfo is whether [ConnTFOInfo] is not supported on the current platform.
+const comptimeNoTFOInfo = true
+
+func getTFOInfo(fd uintptr) (TFOInfo, error) {
+	return TFOInfo{}, ErrUnsupported
+}
+
+func getAcceptInfo(fd uintptr) (AcceptInfo, error) {
+	return AcceptInfo{}, ErrUnsupported
+}
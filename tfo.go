@@ -13,7 +13,9 @@ import (
 	"context"
 	"errors"
 	"net"
+	"net/netip"
 	"os"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -54,6 +56,62 @@ type ListenConfig struct {
 	// Fallback controls whether to proceed without TFO when TFO is enabled but not supported
 	// on the system.
 	Fallback bool
+
+	// TFOKeys, if not empty, sets the listener's own TFO cookie keys, so that
+	// listeners sharing the same keys issue and accept the same cookies.
+	// The first key is the primary key, and the optional second key is the backup key.
+	// See [SetTFOKeys] for details.
+	//
+	// This is only supported on Linux. On other platforms, Listen fails unless
+	// [ListenConfig.Fallback] is set to true, in which case the keys are ignored.
+	TFOKeys []TFOKey
+
+	// KeyRotator, if not nil, registers listeners created by Listen for TFO key rotation.
+	// The keys installed by the rotator replace [ListenConfig.TFOKeys].
+	//
+	// This is only supported on Linux. On other platforms, Listen fails unless
+	// [ListenConfig.Fallback] is set to true, in which case the rotator is not used.
+	KeyRotator *KeyRotator
+
+	// NoCookie controls whether to accept data in SYN without a valid TFO cookie.
+	// Clients must be configured to send data in SYN without a cookie, e.g. with [Dialer.NoCookie].
+	//
+	// This is only supported on Linux. On other platforms, Listen fails unless
+	// [ListenConfig.Fallback] is set to true, in which case cookies are required as usual.
+	NoCookie bool
+
+	// SocketOptions is set on the listener socket before it starts listening,
+	// whether TFO is enabled or not.
+	SocketOptions SocketOptions
+
+	// Stats, if not nil, collects counters about listen calls.
+	Stats *Stats
+}
+
+// setTFOKeys applies [ListenConfig.TFOKeys] to the listener socket.
+func (lc *ListenConfig) setTFOKeys(fd uintptr) error {
+	if len(lc.TFOKeys) == 0 {
+		return nil
+	}
+	if err := setTFOKeys(fd, lc.TFOKeys); err != nil {
+		if !lc.Fallback || !errors.Is(err, ErrUnsupported) {
+			return wrapSyscallError("setsockopt(TCP_FASTOPEN_KEY)", err)
+		}
+	}
+	return nil
+}
+
+// setTFONoCookie applies [ListenConfig.NoCookie] to the listener socket.
+func (lc *ListenConfig) setTFONoCookie(fd uintptr) error {
+	if !lc.NoCookie {
+		return nil
+	}
+	if err := setTFONoCookie(fd); err != nil {
+		if !lc.Fallback || !errors.Is(err, ErrUnsupported) {
+			return wrapSyscallError("setsockopt(TCP_FASTOPEN_NO_COOKIE)", err)
+		}
+	}
+	return nil
 }
 
 func (lc *ListenConfig) tfoDisabled() bool {
@@ -73,9 +131,24 @@ func (lc *ListenConfig) TFO() bool {
 // unless [ListenConfig.Backlog] is negative or [ListenConfig.DisableTFO] is set to true.
 func (lc *ListenConfig) Listen(ctx context.Context, network, address string) (net.Listener, error) {
 	if lc.tfoDisabled() || !networkIsTCP(network) || lc.tfoNeedsFallback() {
-		return lc.ListenConfig.Listen(ctx, network, address)
+		nlc := lc.ListenConfig
+		nlc.Control = lc.control()
+		return nlc.Listen(ctx, network, address)
 	}
-	return lc.listenTFO(ctx, network, address) // tfo_darwin.go, tfo_listen_generic.go, tfo_unsupported.go
+	lc.Stats.listenStart()
+	ln, err := lc.listenTFO(ctx, network, address) // tfo_darwin.go, tfo_listen_generic.go, tfo_unsupported.go
+	if err != nil {
+		return nil, err
+	}
+	if lc.KeyRotator != nil {
+		if err = lc.KeyRotator.Register(ln.(*net.TCPListener)); err != nil {
+			if !lc.Fallback || !errors.Is(err, ErrUnsupported) {
+				ln.Close()
+				return nil, err
+			}
+		}
+	}
+	return ln, nil
 }
 
 // ListenContext is like [net.ListenContext] but enables TFO whenever possible.
@@ -89,8 +162,9 @@ func Listen(network, address string) (net.Listener, error) {
 	return ListenContext(context.Background(), network, address)
 }
 
-// ListenTCP is like [net.ListenTCP] but enables TFO whenever possible.
-func ListenTCP(network string, laddr *net.TCPAddr) (*net.TCPListener, error) {
+// ListenTCP is like [ListenConfig.Listen] but takes a [*net.TCPAddr] and
+// returns a [*net.TCPListener]. If laddr is nil, a local address is automatically chosen.
+func (lc *ListenConfig) ListenTCP(ctx context.Context, network string, laddr *net.TCPAddr) (*net.TCPListener, error) {
 	if !networkIsTCP(network) {
 		return nil, &net.OpError{Op: "listen", Net: network, Source: nil, Addr: opAddr(laddr), Err: net.UnknownNetworkError(network)}
 	}
@@ -98,12 +172,29 @@ func ListenTCP(network string, laddr *net.TCPAddr) (*net.TCPListener, error) {
 	if laddr != nil {
 		address = laddr.String()
 	}
-	var lc ListenConfig
-	ln, err := lc.listenTFO(context.Background(), network, address) // tfo_darwin.go, tfo_listen_generic.go, tfo_unsupported.go
+	ln, err := lc.Listen(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
-	return ln.(*net.TCPListener), err
+	return ln.(*net.TCPListener), nil
+}
+
+// ListenTCPAddrPort is like [ListenConfig.ListenTCP] but takes a [netip.AddrPort].
+// If laddr is not valid, a local address is automatically chosen.
+func (lc *ListenConfig) ListenTCPAddrPort(ctx context.Context, network string, laddr netip.AddrPort) (*net.TCPListener, error) {
+	return lc.ListenTCP(ctx, network, tcpAddrFromAddrPort(laddr))
+}
+
+// ListenTCP is like [net.ListenTCP] but enables TFO whenever possible.
+func ListenTCP(network string, laddr *net.TCPAddr) (*net.TCPListener, error) {
+	var lc ListenConfig
+	return lc.ListenTCP(context.Background(), network, laddr)
+}
+
+// ListenTCPAddrPort is like [ListenTCP] but takes a [netip.AddrPort].
+func ListenTCPAddrPort(network string, laddr netip.AddrPort) (*net.TCPListener, error) {
+	var lc ListenConfig
+	return lc.ListenTCPAddrPort(context.Background(), network, laddr)
 }
 
 type dialTFOSupport uint32
@@ -122,13 +213,22 @@ func (a *atomicDialTFOSupport) load() dialTFOSupport {
 	return dialTFOSupport(a.v.Load())
 }
 
-func (a *atomicDialTFOSupport) storeNone() {
-	a.v.Store(uint32(dialTFOSupportNone))
+// storeNone disables TFO for the whole process, and returns whether it was enabled.
+func (a *atomicDialTFOSupport) storeNone() bool {
+	return a.v.Swap(uint32(dialTFOSupportNone)) != uint32(dialTFOSupportNone)
+}
+
+// reset forgets the detected support.
+func (a *atomicDialTFOSupport) reset() {
+	a.v.Store(uint32(dialTFOSupportDefault))
 }
 
 var runtimeDialTFOSupport atomicDialTFOSupport
 
 // Dialer wraps [net.Dialer] with an additional option that allows you to disable TFO.
+//
+// The keep-alive options of [net.Dialer], including KeepAliveConfig on Go 1.23 and later,
+// apply to connections dialed with TFO as well.
 type Dialer struct {
 	net.Dialer
 
@@ -142,47 +242,284 @@ type Dialer struct {
 	// On Linux this also controls whether the sendto(MSG_FASTOPEN) fallback path is tried
 	// before giving up on TFO.
 	Fallback bool
+
+	// NoCookie controls whether to send data in SYN without a TFO cookie,
+	// saving the round trip that obtains the cookie. The server must be configured
+	// to accept data in SYN without a cookie, e.g. with [ListenConfig.NoCookie].
+	//
+	// This is only supported on Linux. On other platforms, dial calls fail unless
+	// [Dialer.Fallback] is set to true, in which case cookies are used as usual.
+	NoCookie bool
+
+	// HealthCache, if not nil, makes TFO failures only affect the destination they occurred on,
+	// instead of the whole process. This covers connect calls that reject data in SYN, including
+	// with errors like EPIPE or EOPNOTSUPP on Linux, blackholes detected with [Dialer.SYNDataTimeout],
+	// and data in SYN the destination did not handle, see [HealthCache.Observe].
+	// Destinations with a recorded failure are dialed without TFO until the entry expires.
+	// Only failing to enable TFO on the socket still disables TFO for the whole process.
+	//
+	// On Linux, to observe how the destination handled the data in SYN, dial calls that sent
+	// data in SYN wait for the SYN-ACK before returning.
+	//
+	// HealthCache is only used when [Dialer.Fallback] is set to true.
+	HealthCache *HealthCache
+
+	// SYNDataTimeout, if positive, is the maximum amount of time to wait for the SYN-ACK
+	// after sending a SYN that carries data. If it elapses, the dialer assumes that a middlebox
+	// drops SYNs with data, and retries the same address with a plain SYN, writing the data
+	// after the handshake.
+	//
+	// The blackhole is recorded in [Dialer.HealthCache] if set. Otherwise TFO is disabled
+	// for the whole process for [DefaultBlackholeTimeout], doubled on each consecutive detection,
+	// like the tcp_fastopen_blackhole_timeout_sec sysctl on Linux.
+	//
+	// SYNDataTimeout is only used when [Dialer.Fallback] is set to true.
+	SYNDataTimeout time.Duration
+
+	// HappyEyeballs, if not nil, makes dial calls race the resolved addresses with
+	// Happy Eyeballs version 2 (RFC 8305) instead of [net.Dialer.FallbackDelay].
+	// On Linux, this uses sendto(MSG_FASTOPEN) instead of TCP_FASTOPEN_CONNECT,
+	// as the latter leaves address racing to [net.Dialer].
+	// Dial calls that do not use TFO, e.g. when there is no data to send, also race
+	// the addresses this way instead of handing the dial call to [net.Dialer].
+	HappyEyeballs *HappyEyeballs
+
+	// Lookup, if not nil, is used instead of [net.Dialer.Resolver] to resolve host names
+	// to the addresses to dial, including when TFO is not used. [net.Dialer.Resolver] is still
+	// used to look up service names. network is the network of the dial call: "tcp", "tcp4" or "tcp6".
+	// With a positive [HappyEyeballs.ResolutionDelay], it is called once with "tcp6"
+	// and once with "tcp4" instead, concurrently. It should only return addresses
+	// that can be dialed on network.
+	//
+	// Like [Dialer.HappyEyeballs], on Linux, it makes dial calls use sendto(MSG_FASTOPEN)
+	// instead of TCP_FASTOPEN_CONNECT.
+	Lookup func(ctx context.Context, network, host string) ([]netip.Addr, error)
+
+	// AttemptTimeout, if positive, is the maximum amount of time a connection attempt
+	// to a single address may take, when a host name resolves to several addresses.
+	// The deadline of the dial call still applies.
+	// By default, the time left is split evenly between the remaining addresses,
+	// with each attempt given at least 2 seconds.
+	//
+	// Like [Dialer.HappyEyeballs], on Linux, it makes dial calls use sendto(MSG_FASTOPEN)
+	// instead of TCP_FASTOPEN_CONNECT.
+	AttemptTimeout time.Duration
+
+	// AttemptTimeoutFunc, if not nil, overrides [Dialer.AttemptTimeout] and returns
+	// the timeout for a connection attempt to a single address. remaining is the time left
+	// before the deadline of the dial call, or 0 if it has none. addrsRemaining is the number
+	// of addresses left to try, including this one. A zero or negative timeout means
+	// no limit other than the deadline of the dial call.
+	//
+	// Without [Dialer.HappyEyeballs], when the primary and fallback addresses are raced
+	// as described in [net.Dialer.FallbackDelay], each racer calls AttemptTimeoutFunc
+	// from its own goroutine, so it may be called concurrently.
+	AttemptTimeoutFunc func(remaining time.Duration, addrsRemaining int) time.Duration
+
+	// SingleSYNDataRacer controls whether connection attempts that race each other,
+	// because of [net.Dialer.FallbackDelay] or [Dialer.HappyEyeballs], may carry data in SYN
+	// at the same time. If true, only one attempt at a time carries the data, and the others
+	// are made without TFO. If one of them wins, the data is written after the handshake,
+	// and [DialInfo.RacedWithoutSYNData] is set. This prevents the data from being delivered
+	// to the server by more than one connection, which matters for non-idempotent requests.
+	// The SYN of an abandoned attempt may still have delivered its data,
+	// so see also [Dialer.ReplaySafeLen].
+	//
+	// On Linux, dial calls with TCP_FASTOPEN_CONNECT are not affected, as the data is only
+	// written on the connection that wins the race.
+	SingleSYNDataRacer bool
+
+	// MaxSYNData, if positive, is the maximum number of bytes to send in SYN.
+	// The rest of the data is written after the handshake.
+	// Set to [MaxSYNDataMSS] to cap data in SYN to the default MSS.
+	// If it is 0, data in SYN is only capped by the kernel.
+	MaxSYNData int
+
+	// ReplaySafeLen, if not nil, is called once per dial call with the data to send,
+	// and returns the length of its prefix that is safe to be delivered more than once.
+	// RFC 7413 allows the server application to receive data in SYN twice, so only
+	// that prefix is sent in SYN, and the rest is written after the handshake.
+	// If it returns 0, the connection is dialed without TFO.
+	//
+	// The function must not modify or retain the buffers.
+	ReplaySafeLen func(b net.Buffers) int
+
+	// ZeroCopy controls whether to send the data with MSG_ZEROCOPY, which saves copying
+	// large payloads into the kernel. Dial calls then return once the kernel has released
+	// the buffers, which usually means after the peer acknowledged the data.
+	// Data in SYN is still copied, and is capped to the default MSS, as with [MaxSYNDataMSS],
+	// so that the rest is sent with MSG_ZEROCOPY. Zero-copy sends are slower for small payloads.
+	//
+	// This is only supported on Linux. On other platforms, or if the kernel does not
+	// support SO_ZEROCOPY, the data is copied as usual.
+	ZeroCopy bool
+
+	// SocketOptions is set on every socket created by dial calls before it is bound
+	// and connected, whether TFO is used or not.
+	SocketOptions SocketOptions
+
+	// Trace, if not nil, is called at various stages of TFO dial calls.
+	// See also [WithDialTrace].
+	Trace *DialTrace
+
+	// Stats, if not nil, collects counters about dial calls.
+	Stats *Stats
+}
+
+// tfoHealthy reports whether TFO should be attempted to the destination
+// according to [Dialer.HealthCache].
+func (d *Dialer) tfoHealthy(ap netip.AddrPort) bool {
+	return !d.Fallback || d.HealthCache == nil || d.HealthCache.Healthy(ap)
 }
 
-func (d *Dialer) dialAndWrite(ctx context.Context, network, address string, b []byte) (net.Conn, error) {
-	c, err := d.Dialer.DialContext(ctx, network, address)
+// markTFOFailed records a TFO failure on the destination in [Dialer.HealthCache],
+// or disables TFO for the whole process if there is no health cache.
+func (d *Dialer) markTFOFailed(ap netip.AddrPort) {
+	if d.HealthCache != nil {
+		d.HealthCache.MarkFailed(ap)
+		return
+	}
+	if runtimeDialTFOSupport.storeNone() {
+		d.Stats.runtimeDisable()
+	}
+}
+
+// observesSYNData reports whether dial calls wait for the SYN-ACK of a SYN carrying data,
+// to record how the destination handled the data in [Dialer.HealthCache].
+func (d *Dialer) observesSYNData() bool {
+	return !comptimeNoTFOInfo && d.Fallback && d.HealthCache != nil
+}
+
+// observeSYNData records how the destination handled the data in SYN of tc,
+// whose SYN-ACK has arrived, in [Dialer.HealthCache]. See [HealthCache.Observe].
+func (d *Dialer) observeSYNData(tc *net.TCPConn) {
+	if !d.observesSYNData() {
+		return
+	}
+	if info, err := ConnTFOInfo(tc); err == nil && info.Established {
+		d.HealthCache.Observe(tc.RemoteAddr().(*net.TCPAddr).AddrPort(), info)
+	}
+}
+
+// setTFONoCookie applies [Dialer.NoCookie] to the socket.
+func (d *Dialer) setTFONoCookie(fd uintptr) error {
+	if !d.NoCookie {
+		return nil
+	}
+	if err := setTFONoCookie(fd); err != nil {
+		if !d.Fallback || !errors.Is(err, ErrUnsupported) {
+			return newTFOError(TFOStageSockopt, "TCP_FASTOPEN_NO_COOKIE", wrapSyscallError("setsockopt(TCP_FASTOPEN_NO_COOKIE)", err))
+		}
+	}
+	return nil
+}
+
+func (d *Dialer) dialAndWrite(ctx context.Context, network, address string, bufs [][]byte) (net.Conn, error) {
+	c, err := d.dialNet(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
-	if err = netConnWriteBytes(ctx, c, b); err != nil {
+	if err = netConnWriteBuffers(ctx, c, bufs); err != nil {
 		c.Close()
 		return nil, err
 	}
 	return c, nil
 }
 
-func (d *Dialer) dialAndWriteTCPConn(ctx context.Context, network, address string, b []byte) (*net.TCPConn, error) {
-	c, err := d.Dialer.DialContext(ctx, network, address)
+// racesAddrs reports whether dial calls must resolve and race the addresses themselves,
+// because [net.Dialer] does not support some of the options that are set.
+func (d *Dialer) racesAddrs() bool {
+	return d.HappyEyeballs != nil || d.Lookup != nil || d.AttemptTimeout > 0 || d.AttemptTimeoutFunc != nil
+}
+
+// attemptTimeout returns the timeout set by [Dialer.AttemptTimeout] or [Dialer.AttemptTimeoutFunc]
+// for a connection attempt, and whether one of them is set.
+func (d *Dialer) attemptTimeout(remaining time.Duration, addrsRemaining int) (time.Duration, bool) {
+	switch {
+	case d.AttemptTimeoutFunc != nil:
+		return d.AttemptTimeoutFunc(remaining, addrsRemaining), true
+	case d.AttemptTimeout > 0:
+		return d.AttemptTimeout, true
+	default:
+		return 0, false
+	}
+}
+
+// dialWithoutTFO dials address without TFO and writes bufs.
+func (d *Dialer) dialWithoutTFO(ctx context.Context, network, address string, bufs [][]byte, info *DialInfo) (net.Conn, error) {
+	if d.racesAddrs() && networkIsTCP(network) {
+		tc, err := d.dialTCPWithoutTFO(ctx, network, address, bufs, 0, info) // tfo_supported.go, tfo_connect_stub.go
+		if err != nil {
+			return nil, err
+		}
+		return tc, nil
+	}
+	if buffersLen(bufs) == 0 {
+		return d.dialNet(ctx, network, address)
+	}
+	return d.dialAndWrite(ctx, network, address, bufs)
+}
+
+// dialFallbackTCPConn reports a fallback for reason, then dials address without TFO and writes bufs.
+func (d *Dialer) dialFallbackTCPConn(ctx context.Context, network, address string, bufs [][]byte, reason FallbackReason, info *DialInfo) (*net.TCPConn, error) {
+	if d.racesAddrs() {
+		// Each connection attempt is reported as a fallback instead.
+		return d.dialTCPWithoutTFO(ctx, network, address, bufs, reason, info)
+	}
+	dialFallback(ctx, info, network, address, reason, nil)
+	return d.dialAndWriteTCPConn(ctx, network, address, bufs, info)
+}
+
+func (d *Dialer) dialAndWriteTCPConn(ctx context.Context, network, address string, bufs [][]byte, info *DialInfo) (*net.TCPConn, error) {
+	c, err := d.dialNet(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
-	if err = netConnWriteBytes(ctx, c, b); err != nil {
+	if err = netConnWriteBuffers(ctx, c, bufs); err != nil {
 		c.Close()
 		return nil, err
 	}
+	info.Method = DialMethodFallback
+	info.SYNDataLen = 0
 	return c.(*net.TCPConn), nil
 }
 
 // TFO returns true if the next dial call will attempt to enable TFO.
+// It does not take [Dialer.HealthCache] into account.
 func (d *Dialer) TFO() bool {
-	return !d.DisableTFO && (!d.Fallback || !comptimeDialNoTFO && runtimeDialTFOSupport.load() != dialTFOSupportNone)
+	return !d.DisableTFO && (!d.Fallback || !comptimeDialNoTFO && runtimeDialTFOSupport.load() != dialTFOSupportNone && !d.tfoBlackholed())
 }
 
 // DialContext is like [net.Dialer.DialContext] but enables TFO whenever possible,
 // unless [Dialer.DisableTFO] is set to true.
 func (d *Dialer) DialContext(ctx context.Context, network, address string, b []byte) (net.Conn, error) {
-	if len(b) == 0 {
-		return d.Dialer.DialContext(ctx, network, address)
+	var info DialInfo
+	bufs := getSingleBuffer(b)
+	defer putSingleBuffer(bufs)
+	return d.dialContext(ctx, network, address, bufs[:], &info)
+}
+
+// DialContextBuffers is like [Dialer.DialContext] but takes the data in SYN as a vector of buffers.
+//
+// On Linux and FreeBSD, the buffers are passed to the kernel as is, without being copied
+// into a single buffer. On other platforms, they may be concatenated first.
+// The buffers are not modified.
+func (d *Dialer) DialContextBuffers(ctx context.Context, network, address string, b net.Buffers) (net.Conn, error) {
+	var info DialInfo
+	return d.dialContext(ctx, network, address, b, &info)
+}
+
+func (d *Dialer) dialContext(ctx context.Context, network, address string, bufs [][]byte, info *DialInfo) (net.Conn, error) {
+	if buffersLen(bufs) == 0 || d.DisableTFO || !networkIsTCP(network) {
+		return d.dialWithoutTFO(ctx, network, address, bufs, info)
 	}
-	if d.DisableTFO || !networkIsTCP(network) {
-		return d.dialAndWrite(ctx, network, address, b)
+	limit := d.synDataLimit(bufs)
+	if limit == 0 {
+		return d.dialWithoutTFO(ctx, network, address, bufs, info)
 	}
-	tc, err := d.dialTFO(ctx, network, address, b) // tfo_bsd+windows.go, tfo_linux.go, tfo_unsupported.go
+	d.Stats.dialStart()
+	tc, err := d.dialTFO(d.withTrace(ctx), network, address, bufs, dialOptions{synDataLimit: limit}, info) // tfo_bsd+windows.go, tfo_linux.go, tfo_unsupported.go
+	d.Stats.dialDone(info, err)
 	if err != nil {
 		return nil, err // return nil [net.Conn] instead of non-nil [net.Conn] with nil [*net.TCPConn] pointer
 	}
@@ -222,6 +559,104 @@ func DialTCP(network string, laddr, raddr *net.TCPAddr, b []byte) (*net.TCPConn,
 	return dialTCPAddr(network, laddr, raddr, b) // tfo_bsd+windows.go, tfo_linux.go, tfo_unsupported.go
 }
 
+// DialTCPContext is like [Dialer.DialContext] but takes [*net.TCPAddr] addresses and
+// returns a [*net.TCPConn]. If laddr is nil, [Dialer.LocalAddr] is used.
+func (d *Dialer) DialTCPContext(ctx context.Context, network string, laddr, raddr *net.TCPAddr, b []byte) (*net.TCPConn, error) {
+	if !networkIsTCP(network) {
+		return nil, &net.OpError{Op: "dial", Net: network, Source: opAddr(laddr), Addr: opAddr(raddr), Err: net.UnknownNetworkError(network)}
+	}
+	if raddr == nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Source: opAddr(laddr), Addr: nil, Err: errMissingAddress}
+	}
+	ld := *d
+	if laddr != nil {
+		ld.LocalAddr = laddr
+	}
+	c, err := ld.DialContext(ctx, network, raddr.String(), b)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*net.TCPConn), nil
+}
+
+// DialTCPAddrPort is like [Dialer.DialTCPContext] but takes [netip.AddrPort] addresses.
+// If laddr is not valid, [Dialer.LocalAddr] is used.
+func (d *Dialer) DialTCPAddrPort(ctx context.Context, network string, laddr, raddr netip.AddrPort, b []byte) (*net.TCPConn, error) {
+	return d.DialTCPContext(ctx, network, tcpAddrFromAddrPort(laddr), tcpAddrFromAddrPort(raddr), b)
+}
+
+// DialTCPAddrPort is like [DialTCP] but takes [netip.AddrPort] addresses.
+func DialTCPAddrPort(network string, laddr, raddr netip.AddrPort, b []byte) (*net.TCPConn, error) {
+	return DialTCP(network, tcpAddrFromAddrPort(laddr), tcpAddrFromAddrPort(raddr), b)
+}
+
+// DialAddrPorts is like [Dialer.DialContext] but dials a list of pre-resolved addresses
+// instead of resolving a host name, so [net.Dialer.Resolver] is not used.
+// The addresses are raced the same way as resolved addresses, with the same
+// deadline and fallback semantics. Invalid addresses, and addresses that do not match
+// network or the family of [Dialer.LocalAddr], are skipped.
+//
+// On Linux, this uses sendto(MSG_FASTOPEN) instead of TCP_FASTOPEN_CONNECT.
+func (d *Dialer) DialAddrPorts(ctx context.Context, network string, raddrs []netip.AddrPort, b []byte) (*net.TCPConn, error) {
+	var info DialInfo
+	bufs := getSingleBuffer(b)
+	defer putSingleBuffer(bufs)
+	return d.dialAddrPorts(ctx, network, raddrs, bufs[:], &info)
+}
+
+func (d *Dialer) dialAddrPorts(ctx context.Context, network string, raddrs []netip.AddrPort, bufs [][]byte, info *DialInfo) (*net.TCPConn, error) {
+	if !networkIsTCP(network) {
+		return nil, &net.OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: nil, Err: net.UnknownNetworkError(network)}
+	}
+	ras := make([]*net.TCPAddr, 0, len(raddrs))
+	for _, ap := range raddrs {
+		if ra := tcpAddrFromAddrPort(ap); ra != nil {
+			ras = append(ras, ra)
+		}
+	}
+	if buffersLen(bufs) == 0 || d.DisableTFO {
+		return d.dialTCPAddrs(ctx, network, ras, bufs, dialOptions{noTFO: true}, info) // tfo_supported.go, tfo_connect_stub.go
+	}
+	limit := d.synDataLimit(bufs)
+	if limit == 0 {
+		return d.dialTCPAddrs(ctx, network, ras, bufs, dialOptions{noTFO: true}, info)
+	}
+	d.Stats.dialStart()
+	tc, err := d.dialTFOAddrs(d.withTrace(ctx), network, ras, bufs, dialOptions{synDataLimit: limit}, info) // tfo_bsd+windows.go, tfo_linux.go, tfo_connect_stub.go
+	d.Stats.dialDone(info, err)
+	return tc, err
+}
+
+// dialTCPAddrWithoutTFO dials ra without TFO and writes bufs.
+// If reason is not zero, the attempt is reported as a fallback for reason.
+func (d *Dialer) dialTCPAddrWithoutTFO(ctx context.Context, network string, ra *net.TCPAddr, bufs [][]byte, reason FallbackReason, info *DialInfo) (*net.TCPConn, error) {
+	if reason != 0 {
+		dialFallback(ctx, info, network, ra.String(), reason, nil)
+		return d.dialAndWriteTCPConn(ctx, network, ra.String(), bufs, info)
+	}
+	c, err := d.dialAndWrite(ctx, network, ra.String(), bufs)
+	if err != nil {
+		return nil, err
+	}
+	info.Method = DialMethodNoTFO
+	info.SYNDataLen = 0
+	return c.(*net.TCPConn), nil
+}
+
+// dialOptions is the state of a dial call that is handed down to its connection attempts.
+type dialOptions struct {
+	// noTFO makes connection attempts dial without TFO.
+	// If fallbackReason is not zero, each of them is reported as a fallback for it.
+	noTFO          bool
+	fallbackReason FallbackReason
+
+	// synDataLimit, if positive, is the limit returned by [Dialer.synDataLimit].
+	synDataLimit int
+
+	// race is the *synDataRace of the dial call for [Dialer.SingleSYNDataRacer].
+	race *synDataRace
+}
+
 func networkIsTCP(network string) bool {
 	switch network {
 	case "tcp", "tcp4", "tcp6":
@@ -231,6 +666,18 @@ func networkIsTCP(network string) bool {
 	}
 }
 
+// networkMatchesAddr reports whether ip can be dialed on network.
+func networkMatchesAddr(network string, ip net.IP) bool {
+	switch network {
+	case "tcp4":
+		return ip.To4() != nil
+	case "tcp6":
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
+
 func opAddr(a *net.TCPAddr) net.Addr {
 	if a == nil {
 		return nil
@@ -238,6 +685,14 @@ func opAddr(a *net.TCPAddr) net.Addr {
 	return a
 }
 
+// tcpAddrFromAddrPort is like [net.TCPAddrFromAddrPort] but returns nil if ap is not valid.
+func tcpAddrFromAddrPort(ap netip.AddrPort) *net.TCPAddr {
+	if !ap.IsValid() {
+		return nil
+	}
+	return net.TCPAddrFromAddrPort(ap)
+}
+
 // wrapSyscallError takes an error and a syscall name. If the error is
 // a syscall.Errno, it wraps it in a os.SyscallError using the syscall name.
 func wrapSyscallError(name string, err error) error {
@@ -277,3 +732,74 @@ func netConnWriteBytes(ctx context.Context, c net.Conn, b []byte) error {
 		return err
 	})
 }
+
+// netConnWriteBuffers is like [netConnWriteBytes] but writes a vector of buffers,
+// with a single writev(2) call where supported. bufs is not modified.
+func netConnWriteBuffers(ctx context.Context, c net.Conn, bufs [][]byte) error {
+	if len(bufs) == 1 {
+		return netConnWriteBytes(ctx, c, bufs[0])
+	}
+	v := make(net.Buffers, len(bufs))
+	copy(v, bufs)
+	return connWriteFunc(ctx, c, func(c net.Conn) error {
+		_, err := v.WriteTo(c)
+		return err
+	})
+}
+
+// singleBufferPool holds the vectors that pass the buffer of [Dialer.DialContext] and the like
+// down the dial path, so that one is not allocated on every call. The dial path therefore
+// must not retain bufs after returning: connection attempts that may outlive the dial call,
+// because they lost a race, work on a copy.
+var singleBufferPool = sync.Pool{
+	New: func() any { return new([1][]byte) },
+}
+
+// getSingleBuffer returns a vector from singleBufferPool that holds only b.
+func getSingleBuffer(b []byte) *[1][]byte {
+	bufs := singleBufferPool.Get().(*[1][]byte)
+	bufs[0] = b
+	return bufs
+}
+
+// putSingleBuffer returns bufs to singleBufferPool.
+func putSingleBuffer(bufs *[1][]byte) {
+	bufs[0] = nil
+	singleBufferPool.Put(bufs)
+}
+
+// buffersLen returns the total length of bufs.
+func buffersLen(bufs [][]byte) (n int) {
+	for _, b := range bufs {
+		n += len(b)
+	}
+	return n
+}
+
+// consumeBuffers returns bufs without its first n bytes. bufs is not modified.
+func consumeBuffers(bufs [][]byte, n int) [][]byte {
+	for len(bufs) > 0 && n >= len(bufs[0]) {
+		n -= len(bufs[0])
+		bufs = bufs[1:]
+	}
+	if n == 0 {
+		return bufs
+	}
+	rest := make([][]byte, len(bufs))
+	copy(rest, bufs)
+	rest[0] = rest[0][n:]
+	return rest
+}
+
+// flattenBuffers returns the concatenation of bufs,
+// without copying if there is only one buffer.
+func flattenBuffers(bufs [][]byte) []byte {
+	if len(bufs) == 1 {
+		return bufs[0]
+	}
+	b := make([]byte, 0, buffersLen(bufs))
+	for _, buf := range bufs {
+		b = append(b, buf...)
+	}
+	return b
+}
@@ -33,7 +33,7 @@ func ctrlNetwork(network string, family int) string {
 	return "tcp6"
 }
 
-func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *net.TCPAddr, b []byte, ctrlCtxFn func(context.Context, string, string, syscall.RawConn) error) (*net.TCPConn, error) {
+func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *net.TCPAddr, bufs [][]byte, synDataLimit int, ctrlCtxFn func(context.Context, string, string, syscall.RawConn) error, info *DialInfo) (*net.TCPConn, error) {
 	ltsa := (*tcpSockaddr)(laddr)
 	rtsa := (*tcpSockaddr)(raddr)
 	family, ipv6only := favoriteAddrFamily(network, ltsa, rtsa, "dial")
@@ -48,7 +48,15 @@ func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *n
 		return nil, err
 	}
 
+	// Like [net.Dialer], do not start connecting if ctx is already done.
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	trace := ContextDialTrace(ctx)
+
 	fd, err := d.socket(family)
+	trace.socketCreated(network, raddr.String(), err)
 	if err != nil {
 		return nil, wrapSyscallError("socket", err)
 	}
@@ -58,17 +66,40 @@ func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *n
 		return nil, wrapSyscallError("setsockopt(IPV6_V6ONLY)", err)
 	}
 
-	if err = setNoDelay(fd, 1); err != nil {
+	if err = d.SocketOptions.apply(uintptr(fd), family == unix.AF_INET6); err != nil {
 		unix.Close(fd)
-		return nil, wrapSyscallError("setsockopt(TCP_NODELAY)", err)
+		return nil, err
 	}
 
-	if err = setTFODialerFromSocket(uintptr(fd)); err != nil {
+	if !d.SocketOptions.DisableNoDelay {
+		if err = setNoDelay(fd, 1); err != nil {
+			unix.Close(fd)
+			return nil, wrapSyscallError("setsockopt(TCP_NODELAY)", err)
+		}
+	}
+
+	method := DialMethodSendmsg
+	err = setTFODialerFromSocket(uintptr(fd))
+	trace.tfoSockopt(network, raddr.String(), err)
+	if err != nil {
+		tfoErr := newTFOError(TFOStageSockopt, setTFODialerFromSocketSockoptName, wrapSyscallError("setsockopt("+setTFODialerFromSocketSockoptName+")", err))
 		if !d.Fallback || !errors.Is(err, ErrUnsupported) {
 			unix.Close(fd)
-			return nil, wrapSyscallError("setsockopt("+setTFODialerFromSocketSockoptName+")", err)
+			return nil, tfoErr
 		}
-		runtimeDialTFOSupport.storeNone()
+		if runtimeDialTFOSupport.storeNone() {
+			d.Stats.runtimeDisable()
+		}
+		dialFallback(ctx, info, network, raddr.String(), FallbackReasonUnsupported, tfoErr)
+		method = DialMethodFallback
+	} else if err = d.setTFONoCookie(uintptr(fd)); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	var zc *zeroCopy
+	if d.ZeroCopy && method == DialMethodSendmsg {
+		zc = newZeroCopy(uintptr(fd))
 	}
 
 	f := os.NewFile(uintptr(fd), "")
@@ -98,36 +129,98 @@ func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *n
 
 	var (
 		n           int
+		inProgress  bool
 		canFallback bool
 	)
 
-	if err = connWriteFunc(ctx, f, func(f *os.File) (err error) {
-		n, canFallback, err = connect(rawConn, rsa, b)
+	ipv6 := family == unix.AF_INET6
+	synBufs := zc.synData(d.synData(bufs, synDataLimit, ipv6), ipv6)
+
+	err = connWriteFunc(ctx, f, func(f *os.File) (err error) {
+		n, inProgress, canFallback, err = connect(rawConn, rsa, synBufs)
 		return err
-	}); err != nil {
+	})
+
+	// Only the wait for the SYN-ACK of a SYN that carried data is subject to
+	// [Dialer.SYNDataTimeout], not the plain handshake of a SYN without data.
+	synCtx := ctx
+	if err == nil && n > 0 {
+		var cancel context.CancelFunc
+		synCtx, cancel = d.synDataCtx(ctx)
+		defer cancel()
+		if inProgress || synCtx != ctx || d.observesSYNData() {
+			err = connWriteFunc(synCtx, f, func(f *os.File) error {
+				return waitSYNACK(rawConn, connectSyscallName)
+			})
+		}
+	}
+
+	if err != nil {
+		if method == DialMethodFallback {
+			return nil, err
+		}
+		tfoErr := newTFOError(TFOStageConnect, connectSyscallName, err)
 		if d.Fallback && canFallback {
-			runtimeDialTFOSupport.storeNone()
-			return d.dialAndWriteTCPConn(ctx, network, raddr.String(), b)
+			d.markTFOFailed(raddr.AddrPort())
+			dialFallback(ctx, info, network, raddr.String(), FallbackReasonUnsupported, tfoErr)
+			return d.dialAndWriteTCPConn(ctx, network, raddr.String(), bufs, info)
 		}
-		return nil, err
+		if synDataTimedOut(ctx, synCtx) {
+			f.Close()
+			d.markTFOBlackhole(raddr.AddrPort())
+			dialFallback(ctx, info, network, raddr.String(), FallbackReasonBlackhole, tfoErr)
+			return d.dialAndWriteTCPConn(ctx, network, raddr.String(), bufs, info)
+		}
+		// Only errors from the connect call itself are TFO errors, not cancellation or deadlines.
+		if !isSyscallError(err, connectSyscallName) {
+			return nil, err
+		}
+		return nil, tfoErr
+	}
+	if n > 0 && synCtx != ctx {
+		d.markTFOSYNDataAcked()
+	}
+	if method == DialMethodSendmsg {
+		trace.synData(network, raddr.String(), n)
 	}
 
 	c, err := net.FileConn(f)
 	if err != nil {
 		return nil, err
 	}
+	tc := c.(*net.TCPConn)
 
-	if n < len(b) {
-		if err = netConnWriteBytes(ctx, c, b[n:]); err != nil {
-			c.Close()
-			return nil, err
-		}
+	if n > 0 {
+		d.observeSYNData(tc)
 	}
 
-	return c.(*net.TCPConn), err
+	if d.SocketOptions.DisableNoDelay {
+		// [net.FileConn] sets TCP_NODELAY.
+		err = tc.SetNoDelay(false)
+	}
+	if err == nil && n < buffersLen(bufs) {
+		err = zc.writeBuffers(ctx, tc, consumeBuffers(bufs, n))
+	}
+	if err == nil {
+		err = zc.release(ctx, tc)
+	}
+	if err != nil {
+		zc.abort(tc)
+		tc.Close()
+		return nil, err
+	}
+
+	info.Method = method
+	if method == DialMethodSendmsg {
+		info.SYNDataLen = n
+	}
+	return tc, nil
 }
 
-func connect(rawConn syscall.RawConn, rsa syscall.Sockaddr, b []byte) (n int, canFallback bool, err error) {
+// connect initiates the connection with bufs as the SYN data. If no data went out
+// with the SYN, it waits for the handshake to complete. Otherwise inProgress reports
+// whether the handshake is still in progress, for the caller to wait with [waitSYNACK].
+func connect(rawConn syscall.RawConn, rsa syscall.Sockaddr, bufs [][]byte) (n int, inProgress, canFallback bool, err error) {
 	var done bool
 
 	if perr := rawConn.Write(func(fd uintptr) bool {
@@ -135,30 +228,64 @@ func connect(rawConn syscall.RawConn, rsa syscall.Sockaddr, b []byte) (n int, ca
 			return true
 		}
 
-		n, err = doConnect(fd, rsa, b)
+		n, err = doConnect(fd, rsa, bufs)
 		if err == unix.EINPROGRESS {
-			done = true
 			err = nil
+			if n > 0 {
+				inProgress = true
+				return true
+			}
+			done = true
 			return false
 		}
 		return true
 	}); perr != nil {
-		return 0, false, perr
+		return 0, false, false, perr
 	}
 
 	if err != nil {
-		return 0, doConnectCanFallback(err), wrapSyscallError(connectSyscallName, err)
+		return 0, false, doConnectCanFallback(err), wrapSyscallError(connectSyscallName, err)
+	}
+
+	if inProgress {
+		return
 	}
 
 	if perr := rawConn.Control(func(fd uintptr) {
 		err = getSocketError(int(fd), connectSyscallName)
 	}); perr != nil {
-		return 0, false, perr
+		return 0, false, false, perr
 	}
 
 	return
 }
 
+// waitSYNACK waits until the TCP socket is no longer waiting for the SYN-ACK,
+// and returns the error of the connection attempt, if any.
+func waitSYNACK(rawConn syscall.RawConn, call string) error {
+	var err error
+
+	if perr := rawConn.Write(func(fd uintptr) bool {
+		var synSent bool
+		synSent, err = socketSYNSent(fd)
+		return err != nil || !synSent
+	}); perr != nil {
+		return perr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if perr := rawConn.Control(func(fd uintptr) {
+		err = getSocketError(int(fd), call)
+	}); perr != nil {
+		return perr
+	}
+
+	return err
+}
+
 func getSocketError(fd int, call string) error {
 	nerr, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ERROR)
 	if err != nil {
@@ -7,21 +7,21 @@ import (
 	"net"
 )
 
-func (d *Dialer) dialTFO(ctx context.Context, network, address string, b []byte) (*net.TCPConn, error) {
-	if d.Fallback && runtimeDialTFOSupport.load() == dialTFOSupportNone {
-		return d.dialAndWriteTCPConn(ctx, network, address, b)
+func (d *Dialer) dialTFO(ctx context.Context, network, address string, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	if d.Fallback && runtimeDialTFOSupport.load() == dialTFOSupportNone || d.tfoBlackholed() {
+		return d.dialFallbackTCPConn(ctx, network, address, bufs, FallbackReasonDisabled, info)
 	}
-	return d.dialTFOFromSocket(ctx, network, address, b)
+	return d.dialTFOFromSocket(ctx, network, address, bufs, opts, info)
 }
 
 func dialTCPAddr(network string, laddr, raddr *net.TCPAddr, b []byte) (*net.TCPConn, error) {
 	var d Dialer
 	setMultipathTCP(d.Dialer, false) // Align with [net.DialTCP].
-	c, err := d.dialSingle(context.Background(), network, laddr, raddr, b, nil)
+	var info DialInfo
+	c, err := d.dialSingle(context.Background(), network, laddr, raddr, [][]byte{b}, 0, nil, &info)
 	if err != nil {
 		return nil, &net.OpError{Op: "dial", Net: network, Source: laddr, Addr: raddr, Err: err}
 	}
-	c.SetKeepAlive(true)
-	c.SetKeepAlivePeriod(defaultTCPKeepAlive)
+	setKeepAlive(c, &d.Dialer)
 	return c, nil
 }
@@ -2,6 +2,8 @@
 
 package tfo
 
+import "golang.org/x/sys/unix"
+
 func setTFODialerFromSocket(fd uintptr) error {
 	return setTFODialer(fd)
 }
@@ -10,3 +12,14 @@ func setTFODialerFromSocket(fd uintptr) error {
 func doConnectCanFallback(err error) bool {
 	return false
 }
+
+// socketSYNSent returns whether the TCP socket is waiting for the SYN-ACK.
+func socketSYNSent(fd uintptr) (bool, error) {
+	if _, err := unix.Getpeername(int(fd)); err != nil {
+		if err == unix.ENOTCONN {
+			return true, nil
+		}
+		return false, wrapSyscallError("getpeername", err)
+	}
+	return false, nil
+}
@@ -5,13 +5,15 @@ package tfo
 import (
 	"context"
 	"net"
+	"net/netip"
+	"time"
 )
 
 const comptimeDialNoTFO = true
 
-func (d *Dialer) dialTFO(ctx context.Context, network, address string, b []byte) (*net.TCPConn, error) {
+func (d *Dialer) dialTFO(ctx context.Context, network, address string, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
 	if d.Fallback {
-		return d.dialAndWriteTCPConn(ctx, network, address, b)
+		return d.dialFallbackTCPConn(ctx, network, address, bufs, FallbackReasonUnsupported, info)
 	}
 	return nil, ErrPlatformUnsupported
 }
@@ -19,3 +21,84 @@ func (d *Dialer) dialTFO(ctx context.Context, network, address string, b []byte)
 func dialTCPAddr(network string, laddr, raddr *net.TCPAddr, b []byte) (*net.TCPConn, error) {
 	return nil, ErrPlatformUnsupported
 }
+
+func (d *Dialer) dialTFOAddrs(ctx context.Context, network string, ras []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	if d.Fallback {
+		return d.dialTCPAddrs(ctx, network, ras, bufs, dialOptions{noTFO: true, fallbackReason: FallbackReasonUnsupported}, info)
+	}
+	return nil, ErrPlatformUnsupported
+}
+
+// dialTCPAddrs dials ras in sequence without TFO, returning either the first
+// successful connection, or the first error.
+func (d *Dialer) dialTCPAddrs(ctx context.Context, network string, ras []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	var firstErr error
+	for i, ra := range ras {
+		if !networkMatchesAddr(network, ra.IP) {
+			continue
+		}
+		*info = DialInfo{}
+		c, err := d.dialTCPAddrAttempt(ctx, network, ra, len(ras)-i, bufs, opts.fallbackReason, info)
+		if err == nil {
+			return c, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: errMissingAddress}
+	}
+	return nil, firstErr
+}
+
+// dialTCPAddrAttempt is like [Dialer.dialTCPAddrWithoutTFO] but applies
+// [Dialer.AttemptTimeout] and [Dialer.AttemptTimeoutFunc].
+func (d *Dialer) dialTCPAddrAttempt(ctx context.Context, network string, ra *net.TCPAddr, addrsRemaining int, bufs [][]byte, reason FallbackReason, info *DialInfo) (*net.TCPConn, error) {
+	var remaining time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining = time.Until(deadline)
+	}
+	if timeout, ok := d.attemptTimeout(remaining, addrsRemaining); ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return d.dialTCPAddrWithoutTFO(ctx, network, ra, bufs, reason, info)
+}
+
+// dialTCPWithoutTFO dials address without TFO, resolving it with [Dialer.Lookup]
+// or [net.Dialer.Resolver].
+// If reason is not zero, each connection attempt is reported as a fallback for reason.
+func (d *Dialer) dialTCPWithoutTFO(ctx context.Context, network, address string, bufs [][]byte, reason FallbackReason, info *DialInfo) (*net.TCPConn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	portNum, err := d.Resolver.LookupPort(ctx, network, port)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	var ips []netip.Addr
+	if ip, perr := netip.ParseAddr(host); perr == nil {
+		// Like [net.Resolver], do not look up IP literals.
+		ips = []netip.Addr{ip}
+	} else if d.Lookup != nil {
+		ips, err = d.Lookup(ctx, network, host)
+	} else {
+		ips, err = d.Resolver.LookupNetIP(ctx, "ip", host)
+	}
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+	}
+	ras := make([]*net.TCPAddr, 0, len(ips))
+	for _, ip := range ips {
+		if ip.IsValid() {
+			ras = append(ras, &net.TCPAddr{IP: ip.AsSlice(), Port: portNum, Zone: ip.Zone()})
+		}
+	}
+	return d.dialTCPAddrs(ctx, network, ras, bufs, dialOptions{noTFO: true, fallbackReason: reason}, info)
+}
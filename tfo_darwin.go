@@ -17,7 +17,7 @@ func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string)
 	// However, setting TCP_FASTOPEN requires being in the TCPS_LISTEN state,
 	// which means setting it after listen().
 
-	ctrlFn := lc.Control
+	ctrlFn := lc.control()
 	llc := *lc
 	llc.Control = func(network, address string, c syscall.RawConn) (err error) {
 		if ctrlFn != nil {
@@ -33,6 +33,7 @@ func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string)
 		}
 
 		if err != nil {
+			lc.Stats.listenFailure(err)
 			if !lc.Fallback || !errors.Is(err, ErrUnsupported) {
 				return wrapSyscallError("setsockopt(TCP_FASTOPEN_FORCE_ENABLE)", err)
 			}
@@ -52,8 +53,13 @@ func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string)
 		return nil, err
 	}
 
+	var optErr error
 	if cerr := rawConn.Control(func(fd uintptr) {
-		err = setTFOListener(fd)
+		if err = setTFOListener(fd); err == nil {
+			if optErr = lc.setTFOKeys(fd); optErr == nil {
+				optErr = lc.setTFONoCookie(fd)
+			}
+		}
 	}); cerr != nil {
 		ln.Close()
 		return nil, cerr
@@ -61,12 +67,18 @@ func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string)
 
 	if err != nil {
 		ln.Close()
+		lc.Stats.listenFailure(err)
 		if !lc.Fallback || !errors.Is(err, ErrUnsupported) {
 			return nil, wrapSyscallError("setsockopt(TCP_FASTOPEN)", err)
 		}
 		runtimeListenNoTFO.Store(true)
 	}
 
+	if optErr != nil {
+		ln.Close()
+		return nil, optErr
+	}
+
 	return ln, nil
 }
 
@@ -105,7 +117,7 @@ const setTFODialerFromSocketSockoptName = "TCP_FASTOPEN_FORCE_ENABLE"
 
 const connectSyscallName = "connectx"
 
-func doConnect(fd uintptr, rsa syscall.Sockaddr, b []byte) (int, error) {
-	n, err := Connectx(int(fd), 0, nil, rsa, b)
+func doConnect(fd uintptr, rsa syscall.Sockaddr, bufs [][]byte) (int, error) {
+	n, err := Connectx(int(fd), 0, nil, rsa, flattenBuffers(bufs))
 	return int(n), err
 }
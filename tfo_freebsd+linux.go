@@ -18,6 +18,29 @@ func (*Dialer) setIPv6Only(fd int, family int, ipv6only bool) error {
 
 const connectSyscallName = "sendmsg"
 
-func doConnect(fd uintptr, rsa syscall.Sockaddr, b []byte) (int, error) {
-	return syscall.SendmsgN(int(fd), b, nil, rsa, sendtoImplicitConnectFlag|unix.MSG_NOSIGNAL)
+// iovMax is IOV_MAX, the maximum number of buffers a single sendmsg(2) or writev(2) call takes.
+const iovMax = 1024
+
+// capBuffers returns the first iovMax buffers of bufs.
+func capBuffers(bufs [][]byte) [][]byte {
+	if len(bufs) > iovMax {
+		return bufs[:iovMax]
+	}
+	return bufs
+}
+
+func doConnect(fd uintptr, rsa syscall.Sockaddr, bufs [][]byte) (int, error) {
+	return unix.SendmsgBuffers(int(fd), capBuffers(bufs), nil, unixSockaddr(rsa), sendtoImplicitConnectFlag|unix.MSG_NOSIGNAL)
+}
+
+// unixSockaddr converts a TCP [syscall.Sockaddr] to a [unix.Sockaddr].
+func unixSockaddr(sa syscall.Sockaddr) unix.Sockaddr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return &unix.SockaddrInet4{Port: sa.Port, Addr: sa.Addr}
+	case *syscall.SockaddrInet6:
+		return &unix.SockaddrInet6{Port: sa.Port, ZoneId: sa.ZoneId, Addr: sa.Addr}
+	default:
+		return nil
+	}
 }
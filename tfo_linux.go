@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net"
+	"net/netip"
+	"sync"
 	"syscall"
 
 	"golang.org/x/sys/unix"
@@ -30,63 +32,276 @@ func (a *atomicDialTFOSupport) casLinuxSendto() bool {
 	return a.v.CompareAndSwap(uint32(dialTFOSupportDefault), uint32(dialTFOSupportLinuxSendto))
 }
 
-func (d *Dialer) dialTFO(ctx context.Context, network, address string, b []byte) (*net.TCPConn, error) {
+func (d *Dialer) dialTFO(ctx context.Context, network, address string, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	trace := ContextDialTrace(ctx)
+	if d.tfoBlackholed() {
+		return d.dialFallbackTCPConn(ctx, network, address, bufs, FallbackReasonDisabled, info)
+	}
 	if d.Fallback {
 		switch runtimeDialTFOSupport.load() {
 		case dialTFOSupportNone:
-			return d.dialAndWriteTCPConn(ctx, network, address, b)
+			return d.dialFallbackTCPConn(ctx, network, address, bufs, FallbackReasonDisabled, info)
 		case dialTFOSupportLinuxSendto:
-			return d.dialTFOFromSocket(ctx, network, address, b)
+			return d.dialTFOFromSocket(ctx, network, address, bufs, opts, info)
 		}
 	}
+	if d.racesAddrs() {
+		return d.dialTFOFromSocket(ctx, network, address, bufs, opts, info)
+	}
 
-	var canFallback bool
+	var (
+		canFallback bool
+		attempts    fastOpenConnectAttempts
+	)
 	ctrlCtxFn := d.ControlContext
 	ctrlFn := d.Control
 	ld := *d
-	ld.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) (err error) {
+	ld.ControlContext = func(ctx context.Context, sockNetwork, address string, c syscall.RawConn) (err error) {
 		switch {
 		case ctrlCtxFn != nil:
-			if err = ctrlCtxFn(ctx, network, address, c); err != nil {
+			if err = ctrlCtxFn(ctx, sockNetwork, address, c); err != nil {
 				return err
 			}
 		case ctrlFn != nil:
-			if err = ctrlFn(network, address, c); err != nil {
+			if err = ctrlFn(sockNetwork, address, c); err != nil {
 				return err
 			}
 		}
 
+		attempts.start(trace, network, address)
+		trace.socketCreated(network, address, nil)
+
+		if ap, perr := netip.ParseAddrPort(address); perr == nil && !d.tfoHealthy(ap) {
+			return nil
+		}
+
+		var optErr error
 		if cerr := c.Control(func(fd uintptr) {
-			err = setTFODialer(fd)
+			if err = setTFODialer(fd); err == nil {
+				optErr = d.setTFONoCookie(fd)
+			}
 		}); cerr != nil {
 			return cerr
 		}
 
+		trace.tfoSockopt(network, address, err)
+
 		if err != nil {
 			if d.Fallback && errors.Is(err, ErrUnsupported) {
 				canFallback = true
 			}
-			return wrapSyscallError("setsockopt(TCP_FASTOPEN_CONNECT)", err)
+			return newTFOError(TFOStageSockopt, "TCP_FASTOPEN_CONNECT", wrapSyscallError("setsockopt(TCP_FASTOPEN_CONNECT)", err))
 		}
-		return nil
+		return optErr
 	}
 
-	nc, err := ld.Dialer.DialContext(ctx, network, address)
+	nc, err := ld.dialNet(ctx, network, address)
 	if err != nil {
+		attempts.done(trace, network, "", err)
 		if d.Fallback && canFallback {
-			runtimeDialTFOSupport.casLinuxSendto()
-			return d.dialTFOFromSocket(ctx, network, address, b)
+			if runtimeDialTFOSupport.casLinuxSendto() {
+				d.Stats.linuxSendtoSwitch()
+			}
+			return d.dialTFOFromSocket(ctx, network, address, bufs, opts, info)
+		}
+		// Only errors from connect(2) itself are TFO errors, not those from
+		// name resolution, cancellation or the Control functions.
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && isSyscallError(opErr.Err, "connect") {
+			opErr.Err = wrapTFOError(TFOStageConnect, "TCP_FASTOPEN_CONNECT", opErr.Err)
 		}
 		return nil, err
 	}
-	if err = netConnWriteBytes(ctx, nc, b); err != nil {
-		nc.Close()
+	tc := nc.(*net.TCPConn)
+	raddr := tc.RemoteAddr().(*net.TCPAddr)
+
+	// [net.Dialer] cancels the attempts that lost the race.
+	attempts.done(trace, network, raddr.String(), context.Canceled)
+
+	// The health cache may have skipped TCP_FASTOPEN_CONNECT for the destination.
+	if d.Fallback && d.HealthCache != nil && !fastOpenConnectEnabled(tc) {
+		dialFallback(ctx, info, network, raddr.String(), FallbackReasonUnhealthy, nil)
+		if err = netConnWriteBuffers(ctx, tc, bufs); err != nil {
+			tc.Close()
+			trace.connectDone(network, raddr.String(), err)
+			return nil, err
+		}
+		trace.connectDone(network, raddr.String(), nil)
+		info.Method = DialMethodFallback
+		info.SYNDataLen = 0
+		return tc, nil
+	}
+
+	synCtx, cancel := d.synDataCtx(ctx)
+	defer cancel()
+
+	n, err := writeFastOpenConnect(ctx, synCtx, synCtx != ctx || d.observesSYNData(), tc, bufs, d.synData(bufs, opts.synDataLimit, raddr.IP.To4() == nil), d.ZeroCopy)
+	if err != nil {
+		tc.Close()
+		if synDataTimedOut(ctx, synCtx) {
+			d.markTFOBlackhole(raddr.AddrPort())
+			tfoErr := newTFOError(TFOStageConnect, "TCP_FASTOPEN_CONNECT", err)
+			trace.connectDone(network, raddr.String(), tfoErr)
+			dialFallback(ctx, info, network, raddr.String(), FallbackReasonBlackhole, tfoErr)
+			return d.dialAndWriteTCPConn(ctx, network, raddr.String(), bufs, info)
+		}
+		err = newTFOError(TFOStageWrite, "TCP_FASTOPEN_CONNECT", err)
+		trace.connectDone(network, raddr.String(), err)
 		return nil, err
 	}
-	return nc.(*net.TCPConn), nil
+	if n > 0 && synCtx != ctx {
+		d.markTFOSYNDataAcked()
+	}
+	if n > 0 {
+		d.observeSYNData(tc)
+	}
+	trace.synData(network, raddr.String(), n)
+	trace.connectDone(network, raddr.String(), nil)
+	info.Method = DialMethodFastOpenConnect
+	info.SYNDataLen = n
+	return tc, nil
+}
+
+// fastOpenConnectAttempts keeps track of the connection attempts [net.Dialer] makes
+// with TCP_FASTOPEN_CONNECT, so that ConnectDone is called once for each ConnectStart.
+type fastOpenConnectAttempts struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+// start calls ConnectStart for a new connection attempt.
+func (a *fastOpenConnectAttempts) start(trace *DialTrace, network, addr string) {
+	trace.connectStart(network, addr)
+	a.mu.Lock()
+	a.addrs = append(a.addrs, addr)
+	a.mu.Unlock()
+}
+
+// done calls ConnectDone with err for the attempts, except for one to winner,
+// which is left to the caller.
+func (a *fastOpenConnectAttempts) done(trace *DialTrace, network, winner string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, addr := range a.addrs {
+		if addr == winner {
+			winner = ""
+			continue
+		}
+		trace.connectDone(network, addr, err)
+	}
+	a.addrs = nil
+}
+
+// fastOpenConnectEnabled returns whether TCP_FASTOPEN_CONNECT is set on the connection's socket.
+func fastOpenConnectEnabled(tc *net.TCPConn) (enabled bool) {
+	rawConn, err := tc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	rawConn.Control(func(fd uintptr) {
+		v, err := unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT)
+		enabled = err == nil && v != 0
+	})
+	return
+}
+
+// writeFastOpenConnect writes bufs to a connection dialed with TCP_FASTOPEN_CONNECT,
+// and returns the number of bytes that went out with the SYN.
+//
+// If the kernel has a TFO cookie for the destination, connect(2) is deferred
+// until the first write, which leaves the socket in SYN_SENT. The first write
+// is then done with a single writev(2) call, so that its byte count is known.
+//
+// The first write only covers synBufs, a prefix of bufs, up to IOV_MAX buffers. If awaitSYNACK is true and
+// data went out with the SYN, the SYN-ACK is awaited with synCtx before the rest
+// of bufs is written.
+//
+// If useZeroCopy is true, the data after the SYN is sent with MSG_ZEROCOPY when supported,
+// and the buffers are released before returning.
+func writeFastOpenConnect(ctx, synCtx context.Context, awaitSYNACK bool, tc *net.TCPConn, bufs, synBufs [][]byte, useZeroCopy bool) (synDataLen int, err error) {
+	rawConn, err := tc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		deferred bool
+		zc       *zeroCopy
+	)
+
+	if cerr := rawConn.Control(func(fd uintptr) {
+		if ti, terr := getTCPInfo(fd); terr == nil {
+			deferred = ti.State == tcpSynSent
+		}
+		if useZeroCopy {
+			zc = newZeroCopy(fd)
+		}
+	}); cerr != nil {
+		return 0, cerr
+	}
+	defer func() {
+		if err != nil {
+			zc.abort(tc)
+		}
+	}()
+
+	var n int
+
+	if deferred {
+		synBufs = capBuffers(zc.synData(synBufs, tc.RemoteAddr().(*net.TCPAddr).IP.To4() == nil))
+		if err = connWriteFunc(ctx, tc, func(tc *net.TCPConn) error {
+			if perr := rawConn.Write(func(fd uintptr) bool {
+				n, err = unix.Writev(int(fd), synBufs)
+				switch err {
+				case nil:
+					return true
+				case unix.EAGAIN, unix.EINTR:
+					n = 0
+					return false
+				default:
+					n = 0
+					return true
+				}
+			}); perr != nil {
+				return perr
+			}
+			return wrapSyscallError("writev", err)
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if n > 0 && awaitSYNACK {
+		if err = connWriteFunc(synCtx, tc, func(tc *net.TCPConn) error {
+			return waitSYNACK(rawConn, "connect")
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if n < buffersLen(bufs) {
+		if err = zc.writeBuffers(ctx, tc, consumeBuffers(bufs, n)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = zc.release(ctx, tc); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// socketSYNSent returns whether the TCP socket is waiting for the SYN-ACK.
+func socketSYNSent(fd uintptr) (bool, error) {
+	ti, err := getTCPInfo(fd)
+	if err != nil {
+		return false, wrapSyscallError("getsockopt(TCP_INFO)", err)
+	}
+	return ti.State == tcpSynSent, nil
 }
 
 func dialTCPAddr(network string, laddr, raddr *net.TCPAddr, b []byte) (*net.TCPConn, error) {
+	var info DialInfo
 	d := Dialer{Dialer: net.Dialer{LocalAddr: laddr}}
-	return d.dialTFO(context.Background(), network, raddr.String(), b)
+	return d.dialTFO(context.Background(), network, raddr.String(), [][]byte{b}, dialOptions{}, &info)
 }
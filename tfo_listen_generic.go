@@ -10,7 +10,7 @@ import (
 )
 
 func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string) (net.Listener, error) {
-	ctrlFn := lc.Control
+	ctrlFn := lc.control()
 	backlog := lc.Backlog
 	llc := *lc
 	llc.Control = func(network, address string, c syscall.RawConn) (err error) {
@@ -20,19 +20,25 @@ func (lc *ListenConfig) listenTFO(ctx context.Context, network, address string)
 			}
 		}
 
+		var optErr error
 		if cerr := c.Control(func(fd uintptr) {
-			err = setTFOListenerWithBacklog(fd, backlog)
+			if err = setTFOListenerWithBacklog(fd, backlog); err == nil {
+				if optErr = lc.setTFOKeys(fd); optErr == nil {
+					optErr = lc.setTFONoCookie(fd)
+				}
+			}
 		}); cerr != nil {
 			return cerr
 		}
 
 		if err != nil {
+			lc.Stats.listenFailure(err)
 			if !lc.Fallback || !errors.Is(err, ErrUnsupported) {
 				return wrapSyscallError("setsockopt(TCP_FASTOPEN)", err)
 			}
 			runtimeListenNoTFO.Store(true)
 		}
-		return nil
+		return optErr
 	}
 	return llc.ListenConfig.Listen(ctx, network, address)
 }
@@ -5,6 +5,7 @@ package tfo
 import (
 	"context"
 	"net"
+	"net/netip"
 	"os"
 	"syscall"
 	"time"
@@ -104,7 +105,34 @@ func (a *tcpSockaddr) toLocal(net string) sockaddr {
 //go:linkname favoriteAddrFamily net.favoriteAddrFamily
 func favoriteAddrFamily(network string, laddr, raddr sockaddr, mode string) (family int, ipv6only bool)
 
-func (d *Dialer) dialTFOFromSocket(ctx context.Context, network, address string, b []byte) (*net.TCPConn, error) {
+func (d *Dialer) dialTFOFromSocket(ctx context.Context, network, address string, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	return d.dialTCP(ctx, network, address, nil, bufs, opts, info)
+}
+
+// dialTFOAddrs dials ras with TFO, unless TFO is known to be unavailable.
+func (d *Dialer) dialTFOAddrs(ctx context.Context, network string, ras []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	if d.Fallback && runtimeDialTFOSupport.load() == dialTFOSupportNone || d.tfoBlackholed() {
+		opts.noTFO, opts.fallbackReason = true, FallbackReasonDisabled
+	}
+	return d.dialTCPAddrs(ctx, network, ras, bufs, opts, info)
+}
+
+// dialTCPWithoutTFO dials address without TFO, resolving and racing the addresses
+// like [Dialer.dialTFOFromSocket]. If reason is not zero, each connection attempt is reported as a fallback for reason.
+func (d *Dialer) dialTCPWithoutTFO(ctx context.Context, network, address string, bufs [][]byte, reason FallbackReason, info *DialInfo) (*net.TCPConn, error) {
+	return d.dialTCP(ctx, network, address, nil, bufs, dialOptions{noTFO: true, fallbackReason: reason}, info)
+}
+
+// dialTCPAddrs is like [Dialer.dialTFOFromSocket] but dials ras instead of resolving an address.
+func (d *Dialer) dialTCPAddrs(ctx context.Context, network string, ras []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	if ras == nil {
+		ras = []*net.TCPAddr{}
+	}
+	return d.dialTCP(ctx, network, "", ras, bufs, opts, info)
+}
+
+// dialTCP dials the addresses address resolves to, or ras if it is not nil.
+func (d *Dialer) dialTCP(ctx context.Context, network, address string, ras []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
 	if ctx == nil {
 		panic("nil context")
 	}
@@ -147,30 +175,62 @@ func (d *Dialer) dialTFOFromSocket(ctx context.Context, network, address string,
 		laddr = la
 	}
 
-	host, port, err := net.SplitHostPort(address)
-	if err != nil {
-		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+	if d.SingleSYNDataRacer {
+		opts.race = &synDataRace{}
+	}
+
+	var (
+		c   *net.TCPConn
+		err error
+	)
+	if ras != nil {
+		c, err = d.dialResolved(ctx, network, laddr, filterTCPAddrs(network, laddr, ras), bufs, opts, info)
+	} else {
+		var (
+			host, port string
+			portNum    int
+		)
+		host, port, err = net.SplitHostPort(address)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		portNum, err = d.Resolver.LookupPort(ctx, network, port)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		}
+		c, err = d.dialResolving(ctx, network, laddr, host, portNum, bufs, opts, info)
 	}
-	portNum, err := d.Resolver.LookupPort(ctx, network, port)
 	if err != nil {
-		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		return nil, err
+	}
+
+	if info.RacedWithoutSYNData {
+		if err = netConnWriteBuffers(ctx, c, bufs); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	setKeepAlive(c, &d.Dialer) // keepalive_go120.go, keepalive_go123.go
+	return c, nil
+}
+
+// dialResolving resolves host and dials the addresses.
+func (d *Dialer) dialResolving(ctx context.Context, network string, laddr *net.TCPAddr, host string, port int, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	if d.HappyEyeballs != nil {
+		return d.dialHappyEyeballs(ctx, network, laddr, host, port, nil, bufs, opts, info)
 	}
-	ipaddrs, err := d.Resolver.LookupIPAddr(ctx, host)
+	addrs, err := d.lookupTCPAddrs(ctx, network, "ip", laddr, host, port)
 	if err != nil {
 		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
 	}
+	return d.dialResolved(ctx, network, laddr, addrs, bufs, opts, info)
+}
 
-	var addrs []*net.TCPAddr
-
-	for _, ipaddr := range ipaddrs {
-		if laddr != nil && !laddr.IP.IsUnspecified() && !matchAddrFamily(laddr.IP, ipaddr.IP) {
-			continue
-		}
-		addrs = append(addrs, &net.TCPAddr{
-			IP:   ipaddr.IP,
-			Port: portNum,
-			Zone: ipaddr.Zone,
-		})
+// dialResolved dials the resolved addresses.
+func (d *Dialer) dialResolved(ctx context.Context, network string, laddr *net.TCPAddr, addrs []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	if d.HappyEyeballs != nil {
+		return d.dialHappyEyeballs(ctx, network, laddr, "", 0, addrs, bufs, opts, info)
 	}
 
 	var primaries, fallbacks []*net.TCPAddr
@@ -182,42 +242,107 @@ func (d *Dialer) dialTFOFromSocket(ctx context.Context, network, address string,
 		primaries = addrs
 	}
 
-	var c *net.TCPConn
 	if len(fallbacks) > 0 {
-		c, err = d.dialParallel(ctx, network, laddr, primaries, fallbacks, b)
-	} else {
-		c, err = d.dialSerial(ctx, network, laddr, primaries, b)
+		return d.dialParallel(ctx, network, laddr, primaries, fallbacks, bufs, opts, info)
+	}
+	return d.dialSerial(ctx, network, laddr, primaries, bufs, opts, info)
+}
+
+// filterTCPAddrs returns the addresses of ras that match network and the family of laddr.
+// The result is not nil, even if it is empty.
+func filterTCPAddrs(network string, laddr *net.TCPAddr, ras []*net.TCPAddr) []*net.TCPAddr {
+	addrs := make([]*net.TCPAddr, 0, len(ras))
+	for _, ra := range ras {
+		if !networkMatchesAddr(network, ra.IP) {
+			continue
+		}
+		if laddr != nil && !laddr.IP.IsUnspecified() && !matchAddrFamily(laddr.IP, ra.IP) {
+			continue
+		}
+		addrs = append(addrs, ra)
 	}
+	return addrs
+}
+
+// lookupTCPAddrs resolves host to the TCP addresses to dial on network,
+// leaving out those that do not match the family of laddr.
+// family is "ip", or "ip4" or "ip6" to only look up addresses of that family.
+func (d *Dialer) lookupTCPAddrs(ctx context.Context, network, family string, laddr *net.TCPAddr, host string, port int) ([]*net.TCPAddr, error) {
+	trace := ContextDialTrace(ctx)
+	trace.dnsStart(host)
+	var (
+		ipaddrs []net.IPAddr
+		err     error
+	)
+	switch {
+	case d.Lookup != nil:
+		var ips []netip.Addr
+		if ip, perr := netip.ParseAddr(host); perr == nil {
+			// Like [net.Resolver], do not look up IP literals.
+			ips = []netip.Addr{ip}
+		} else {
+			switch family {
+			case "ip4":
+				network = "tcp4"
+			case "ip6":
+				network = "tcp6"
+			}
+			ips, err = d.Lookup(ctx, network, host)
+		}
+		for _, ip := range ips {
+			if ip.IsValid() {
+				ipaddrs = append(ipaddrs, net.IPAddr{IP: ip.AsSlice(), Zone: ip.Zone()})
+			}
+		}
+	case family == "ip":
+		ipaddrs, err = d.Resolver.LookupIPAddr(ctx, host)
+	default:
+		var ips []net.IP
+		ips, err = d.Resolver.LookupIP(ctx, family, host)
+		for _, ip := range ips {
+			ipaddrs = append(ipaddrs, net.IPAddr{IP: ip})
+		}
+	}
+	trace.dnsDone(ipaddrs, err)
 	if err != nil {
 		return nil, err
 	}
 
-	if d.KeepAlive >= 0 {
-		c.SetKeepAlive(true)
-		ka := d.KeepAlive
-		if d.KeepAlive == 0 {
-			ka = defaultTCPKeepAlive
+	var addrs []*net.TCPAddr
+
+	for _, ipaddr := range ipaddrs {
+		if laddr != nil && !laddr.IP.IsUnspecified() && !matchAddrFamily(laddr.IP, ipaddr.IP) {
+			continue
 		}
-		c.SetKeepAlivePeriod(ka)
+		addrs = append(addrs, &net.TCPAddr{
+			IP:   ipaddr.IP,
+			Port: port,
+			Zone: ipaddr.Zone,
+		})
 	}
-	return c, nil
+
+	return addrs, nil
 }
 
 // dialParallel races two copies of dialSerial, giving the first a
 // head start. It returns the first established connection and
 // closes the others. Otherwise it returns an error from the first
 // primary address.
-func (d *Dialer) dialParallel(ctx context.Context, network string, laddr *net.TCPAddr, primaries, fallbacks []*net.TCPAddr, b []byte) (*net.TCPConn, error) {
+func (d *Dialer) dialParallel(ctx context.Context, network string, laddr *net.TCPAddr, primaries, fallbacks []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
 	if len(fallbacks) == 0 {
-		return d.dialSerial(ctx, network, laddr, primaries, b)
+		return d.dialSerial(ctx, network, laddr, primaries, bufs, opts, info)
 	}
 
+	// The racers may outlive this call, so they must not share bufs with the caller.
+	bufs = append([][]byte(nil), bufs...)
+
 	returned := make(chan struct{})
 	defer close(returned)
 
 	type dialResult struct {
 		*net.TCPConn
 		error
+		info    DialInfo
 		primary bool
 		done    bool
 	}
@@ -228,9 +353,11 @@ func (d *Dialer) dialParallel(ctx context.Context, network string, laddr *net.TC
 		if !primary {
 			ras = fallbacks
 		}
-		c, err := d.dialSerial(ctx, network, laddr, ras, b)
+		ContextDialTrace(ctx).racerStart(primary, ras)
+		var info DialInfo
+		c, err := d.dialSerial(ctx, network, laddr, ras, bufs, opts, &info)
 		select {
-		case results <- dialResult{TCPConn: c, error: err, primary: primary, done: true}:
+		case results <- dialResult{TCPConn: c, error: err, info: info, primary: primary, done: true}:
 		case <-returned:
 			if c != nil {
 				c.Close()
@@ -262,6 +389,7 @@ func (d *Dialer) dialParallel(ctx context.Context, network string, laddr *net.TC
 
 		case res := <-results:
 			if res.error == nil {
+				*info = res.info
 				return res.TCPConn, nil
 			}
 			if res.primary {
@@ -285,7 +413,7 @@ func (d *Dialer) dialParallel(ctx context.Context, network string, laddr *net.TC
 
 // dialSerial connects to a list of addresses in sequence, returning
 // either the first successful connection, or the first error.
-func (d *Dialer) dialSerial(ctx context.Context, network string, laddr *net.TCPAddr, ras []*net.TCPAddr, b []byte) (*net.TCPConn, error) {
+func (d *Dialer) dialSerial(ctx context.Context, network string, laddr *net.TCPAddr, ras []*net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
 	var firstErr error // The error from the first address is most relevant.
 
 	for i, ra := range ras {
@@ -296,30 +424,22 @@ func (d *Dialer) dialSerial(ctx context.Context, network string, laddr *net.TCPA
 		}
 
 		dialCtx := ctx
-		if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
-			partialDeadline, err := partialDeadline(time.Now(), deadline, len(ras)-i)
-			if err != nil {
-				// Ran out of time.
-				if firstErr == nil {
-					firstErr = &net.OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: ra, Err: err}
-				}
-				break
-			}
-			if partialDeadline.Before(deadline) {
-				var cancel context.CancelFunc
-				dialCtx, cancel = context.WithDeadline(ctx, partialDeadline)
-				defer cancel()
+		partialDeadline, err := d.attemptDeadline(ctx, time.Now(), len(ras)-i)
+		if err != nil {
+			// Ran out of time.
+			if firstErr == nil {
+				firstErr = &net.OpError{Op: "dial", Net: network, Source: d.LocalAddr, Addr: ra, Err: err}
 			}
+			break
 		}
-
-		ctrlCtxFn := d.ControlContext
-		if ctrlCtxFn == nil && d.Control != nil {
-			ctrlCtxFn = func(ctx context.Context, network, address string, c syscall.RawConn) error {
-				return d.Control(network, address, c)
-			}
+		if deadline, hasDeadline := ctx.Deadline(); !partialDeadline.IsZero() && (!hasDeadline || partialDeadline.Before(deadline)) {
+			var cancel context.CancelFunc
+			dialCtx, cancel = context.WithDeadline(ctx, partialDeadline)
+			defer cancel()
 		}
 
-		c, err := d.dialSingle(dialCtx, network, laddr, ra, b, ctrlCtxFn)
+		*info = DialInfo{}
+		c, err := d.dialAddr(dialCtx, network, laddr, ra, bufs, opts, info)
 		if err == nil {
 			return c, nil
 		}
@@ -334,6 +454,44 @@ func (d *Dialer) dialSerial(ctx context.Context, network string, laddr *net.TCPA
 	return nil, firstErr
 }
 
+// dialAddr makes a single connection attempt to ra, with TFO unless
+// [Dialer.HealthCache] has a failure recorded for it.
+// If another attempt is carrying the data, the connection is dialed without TFO,
+// and the data is left for the caller to write if the connection wins the race.
+func (d *Dialer) dialAddr(ctx context.Context, network string, laddr, ra *net.TCPAddr, bufs [][]byte, opts dialOptions, info *DialInfo) (*net.TCPConn, error) {
+	ctrlCtxFn := d.ControlContext
+	if ctrlCtxFn == nil && d.Control != nil {
+		ctrlCtxFn = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			return d.Control(network, address, c)
+		}
+	}
+
+	var (
+		c   *net.TCPConn
+		err error
+	)
+	trace := ContextDialTrace(ctx)
+	trace.connectStart(network, ra.String())
+	carrier := opts.race.acquire()
+	switch {
+	case !carrier:
+		c, err = d.dialTCPAddrWithoutTFO(ctx, network, ra, nil, 0, info)
+		info.RacedWithoutSYNData = err == nil
+	case opts.noTFO:
+		c, err = d.dialTCPAddrWithoutTFO(ctx, network, ra, bufs, opts.fallbackReason, info)
+	case d.tfoHealthy(ra.AddrPort()):
+		c, err = d.dialSingle(ctx, network, laddr, ra, bufs, opts.synDataLimit, ctrlCtxFn, info)
+	default:
+		dialFallback(ctx, info, network, ra.String(), FallbackReasonUnhealthy, nil)
+		c, err = d.dialAndWriteTCPConn(ctx, network, ra.String(), bufs, info)
+	}
+	if err != nil && carrier {
+		opts.race.release()
+	}
+	trace.connectDone(network, ra.String(), err)
+	return c, err
+}
+
 func matchAddrFamily(x, y net.IP) bool {
 	return x.To4() != nil && y.To4() != nil || x.To16() != nil && x.To4() == nil && y.To16() != nil && y.To4() == nil
 }
@@ -384,6 +542,29 @@ func (d *Dialer) deadline(ctx context.Context, now time.Time) (earliest time.Tim
 	return minNonzeroTime(earliest, d.Deadline)
 }
 
+// attemptDeadline returns the deadline to use for a connection attempt to a single address,
+// when addrsRemaining addresses are pending, or the zero time if there is none.
+// It follows [Dialer.AttemptTimeout] or [Dialer.AttemptTimeoutFunc] if set,
+// and [partialDeadline] otherwise.
+func (d *Dialer) attemptDeadline(ctx context.Context, now time.Time, addrsRemaining int) (time.Time, error) {
+	deadline, hasDeadline := ctx.Deadline()
+	var timeRemaining time.Duration
+	if hasDeadline {
+		timeRemaining = deadline.Sub(now)
+		if timeRemaining <= 0 {
+			return time.Time{}, os.ErrDeadlineExceeded
+		}
+	}
+	timeout, ok := d.attemptTimeout(timeRemaining, addrsRemaining)
+	if !ok {
+		return partialDeadline(now, deadline, addrsRemaining)
+	}
+	if timeout <= 0 || hasDeadline && timeout >= timeRemaining {
+		return deadline, nil
+	}
+	return now.Add(timeout), nil
+}
+
 // partialDeadline returns the deadline to use for a single address,
 // when multiple addresses are pending.
 func partialDeadline(now, deadline time.Time, addrsRemaining int) (time.Time, error) {
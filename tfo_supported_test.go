@@ -3,8 +3,17 @@
 package tfo
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 func testClientWriteReadServerReadWriteTCPAddr(listenTCPAddr, dialLocalTCPAddr *net.TCPAddr, t *testing.T) {
@@ -94,3 +103,404 @@ func TestClientWriteReadServerReadWriteTCPAddr(t *testing.T) {
 		})
 	}
 }
+
+func TestSYNDataRace(t *testing.T) {
+	var r synDataRace
+	if !r.acquire() {
+		t.Fatal("acquire() = false on a free race")
+	}
+	if r.acquire() {
+		t.Fatal("acquire() = true on a held race")
+	}
+	r.release()
+	if !r.acquire() {
+		t.Fatal("acquire() = false after release()")
+	}
+
+	var nilRace *synDataRace
+	if !nilRace.acquire() || !nilRace.acquire() {
+		t.Fatal("acquire() = false on a nil race")
+	}
+	nilRace.release()
+}
+
+// TestDialAddrRacedWithoutSYNData ensures that connection attempts made while another attempt
+// carries the data in SYN do not write the data, and that failed attempts that carried it
+// let the next attempt carry it.
+func TestDialAddrRacedWithoutSYNData(t *testing.T) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ra := ln.Addr().(*net.TCPAddr)
+
+	race := &synDataRace{}
+	opts := dialOptions{race: race}
+	race.acquire()
+
+	var d Dialer
+	var info DialInfo
+	c, err := d.dialAddr(context.Background(), "tcp", nil, ra, [][]byte{hello}, opts, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.RacedWithoutSYNData || info.Method != DialMethodNoTFO || info.SYNDataLen != 0 {
+		t.Errorf("info = %+v, want RacedWithoutSYNData with %v", info, DialMethodNoTFO)
+	}
+
+	sc, err := ln.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	c.Close()
+	b, err := io.ReadAll(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 0 {
+		t.Errorf("server read %q, want nothing", b)
+	}
+
+	// A failed attempt that carried the data gives it back.
+	race.release()
+	closedAddr := &net.TCPAddr{IP: net.IPv6loopback, Port: ra.Port}
+	ln.Close()
+	info = DialInfo{}
+	if c, err := d.dialAddr(context.Background(), "tcp", nil, closedAddr, [][]byte{hello}, opts, &info); err == nil {
+		c.Close()
+		t.Fatal("dialAddr() to a closed port succeeded")
+	}
+	if !race.acquire() {
+		t.Error("failed attempt did not release the data in SYN")
+	}
+}
+
+// TestDialSingleSYNDataRacer ensures that dial calls with SingleSYNDataRacer deliver the data once.
+func TestDialSingleSYNDataRacer(t *testing.T) {
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+			for _, he := range []*HappyEyeballs{nil, {}} {
+				d := c.dialer
+				d.SingleSYNDataRacer = true
+				d.HappyEyeballs = he
+				ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv6loopback})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ctrlCh := make(chan struct{})
+				go func() {
+					defer close(ctrlCh)
+					conn, err := ln.AcceptTCP()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					defer conn.Close()
+					readUntilEOF(conn, helloworld, t)
+				}()
+
+				conn, err := d.Dial("tcp", ln.Addr().String(), hello)
+				if err != nil {
+					ln.Close()
+					t.Fatal(err)
+				}
+				write(conn, world, t)
+				conn.(*net.TCPConn).CloseWrite()
+				<-ctrlCh
+				conn.Close()
+				ln.Close()
+			}
+		})
+	}
+}
+
+// TestDialLookup ensures that dial calls resolve host names with [Dialer.Lookup]
+// and pass it the network to look up addresses for.
+func TestDialLookup(t *testing.T) {
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+			for _, tc := range []struct {
+				network      string
+				he           *HappyEyeballs
+				wantNetworks []string
+			}{
+				{"tcp", nil, []string{"tcp"}},
+				{"tcp6", nil, []string{"tcp6"}},
+				{"tcp", &HappyEyeballs{}, []string{"tcp"}},
+				{"tcp", &HappyEyeballs{ResolutionDelay: time.Millisecond}, []string{"tcp4", "tcp6"}},
+			} {
+				d := c.dialer
+				d.HappyEyeballs = tc.he
+				testDialLookup(t, d, tc.network, tc.wantNetworks)
+			}
+		})
+	}
+}
+
+func testDialLookup(t *testing.T, d Dialer, network string, wantNetworks []string) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var (
+		mu       sync.Mutex
+		networks []string
+	)
+	d.Lookup = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		mu.Lock()
+		networks = append(networks, network)
+		mu.Unlock()
+		if host != "tfo.invalid" {
+			t.Errorf("Lookup host = %q, want %q", host, "tfo.invalid")
+		}
+		if network == "tcp4" {
+			return nil, nil
+		}
+		// Invalid addresses are skipped.
+		return []netip.Addr{{}, netip.IPv6Loopback()}, nil
+	}
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, helloworld, t)
+	}()
+
+	address := net.JoinHostPort("tfo.invalid", strconv.Itoa(ln.Addr().(*net.TCPAddr).Port))
+	c, err := d.DialContext(context.Background(), network, address, hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	write(c, world, t)
+	c.(*net.TCPConn).CloseWrite()
+	<-ctrlCh
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(networks)
+	if len(networks) != len(wantNetworks) {
+		t.Fatalf("Lookup networks = %v, want %v", networks, wantNetworks)
+	}
+	for i := range networks {
+		if networks[i] != wantNetworks[i] {
+			t.Errorf("Lookup networks = %v, want %v", networks, wantNetworks)
+			break
+		}
+	}
+}
+
+// TestDialLookupIPLiteral ensures that [Dialer.Lookup] is not called for IP literals.
+func TestDialLookupIPLiteral(t *testing.T) {
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+			for _, he := range []*HappyEyeballs{nil, {}, {ResolutionDelay: time.Millisecond}} {
+				d := c.dialer
+				d.HappyEyeballs = he
+				d.Lookup = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+					t.Errorf("Lookup(%q, %q) called for an IP literal", network, host)
+					return nil, errors.New("unexpected lookup")
+				}
+				testDialLookupIPLiteral(t, d)
+			}
+		})
+	}
+}
+
+func testDialLookupIPLiteral(t *testing.T, d Dialer) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, hello, t)
+	}()
+
+	c, err := d.Dial("tcp", ln.Addr().String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.(*net.TCPConn).CloseWrite()
+	<-ctrlCh
+}
+
+func TestAttemptDeadline(t *testing.T) {
+	now := time.Now()
+	for _, c := range []struct {
+		name           string
+		dialer         Dialer
+		deadline       time.Duration // 0 means no deadline
+		addrsRemaining int
+		want           time.Duration // 0 means no deadline
+	}{
+		{
+			name:           "DefaultNoDeadline",
+			addrsRemaining: 8,
+		},
+		{
+			name:           "DefaultSaneMinimum",
+			deadline:       3 * time.Second,
+			addrsRemaining: 8,
+			want:           2 * time.Second,
+		},
+		{
+			name:           "DefaultEvenSplit",
+			deadline:       10 * time.Second,
+			addrsRemaining: 2,
+			want:           5 * time.Second,
+		},
+		{
+			name:           "AttemptTimeout",
+			dialer:         Dialer{AttemptTimeout: 300 * time.Millisecond},
+			deadline:       3 * time.Second,
+			addrsRemaining: 8,
+			want:           300 * time.Millisecond,
+		},
+		{
+			name:           "AttemptTimeoutNoDeadline",
+			dialer:         Dialer{AttemptTimeout: 300 * time.Millisecond},
+			addrsRemaining: 8,
+			want:           300 * time.Millisecond,
+		},
+		{
+			name:           "AttemptTimeoutAboveDeadline",
+			dialer:         Dialer{AttemptTimeout: 5 * time.Second},
+			deadline:       3 * time.Second,
+			addrsRemaining: 8,
+			want:           3 * time.Second,
+		},
+		{
+			name: "AttemptTimeoutFunc",
+			dialer: Dialer{
+				AttemptTimeout: time.Second,
+				AttemptTimeoutFunc: func(remaining time.Duration, addrsRemaining int) time.Duration {
+					return remaining / time.Duration(addrsRemaining)
+				},
+			},
+			deadline:       3 * time.Second,
+			addrsRemaining: 8,
+			want:           375 * time.Millisecond,
+		},
+		{
+			name: "AttemptTimeoutFuncNoLimit",
+			dialer: Dialer{
+				AttemptTimeoutFunc: func(remaining time.Duration, addrsRemaining int) time.Duration {
+					return 0
+				},
+			},
+			deadline:       3 * time.Second,
+			addrsRemaining: 8,
+			want:           3 * time.Second,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.deadline != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(c.deadline))
+				defer cancel()
+			}
+			got, err := c.dialer.attemptDeadline(ctx, now, c.addrsRemaining)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want time.Time
+			if c.want != 0 {
+				want = now.Add(c.want)
+			}
+			if !got.Equal(want) {
+				t.Errorf("attemptDeadline() = %v, want %v", got.Sub(now), c.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), now)
+	defer cancel()
+	if _, err := (&Dialer{AttemptTimeout: time.Second}).attemptDeadline(ctx, now, 1); err != os.ErrDeadlineExceeded {
+		t.Errorf("attemptDeadline() past the deadline returned %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+}
+
+// TestDialAttemptTimeoutFunc ensures that dial calls consult [Dialer.AttemptTimeoutFunc]
+// for each address they try, whether or not TFO is used.
+func TestDialAttemptTimeoutFunc(t *testing.T) {
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+
+			// With a TFO cookie for the closed address, the first attempt would not fail.
+			closedLn, err := net.Listen("tcp", net.JoinHostPort(noCookieLoopbackHost(), "0"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			closedAddr := closedLn.Addr().(*net.TCPAddr).AddrPort()
+			closedLn.Close()
+
+			ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			var addrsRemaining []int
+			d := c.dialer
+			d.AttemptTimeoutFunc = func(remaining time.Duration, n int) time.Duration {
+				addrsRemaining = append(addrsRemaining, n)
+				return time.Second
+			}
+
+			ctrlCh := make(chan struct{})
+			go func() {
+				defer close(ctrlCh)
+				conn, err := ln.AcceptTCP()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer conn.Close()
+				readUntilEOF(conn, hello, t)
+			}()
+
+			tc, err := d.DialAddrPorts(context.Background(), "tcp4", []netip.AddrPort{closedAddr, ln.Addr().(*net.TCPAddr).AddrPort()}, hello)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tc.Close()
+			tc.CloseWrite()
+			<-ctrlCh
+
+			if len(addrsRemaining) != 2 || addrsRemaining[0] != 2 || addrsRemaining[1] != 1 {
+				t.Errorf("AttemptTimeoutFunc called with addrsRemaining %v, want [2 1]", addrsRemaining)
+			}
+		})
+	}
+}
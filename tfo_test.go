@@ -5,9 +5,12 @@ import (
 	"context"
 	"errors"
 	"io"
+	"math/rand"
 	"net"
+	"net/netip"
 	"os"
 	"runtime"
+	"strconv"
 	"sync"
 	"syscall"
 	"testing"
@@ -498,7 +501,9 @@ func (s *discardTCPServer) Start(t *testing.T) {
 				return
 			}
 
+			s.wg.Add(1)
 			go func() {
+				defer s.wg.Done()
 				defer c.Close()
 
 				n, err := io.Copy(io.Discard, c)
@@ -511,8 +516,8 @@ func (s *discardTCPServer) Start(t *testing.T) {
 	}()
 }
 
-// Close interrupts all running accept goroutines, waits for them to finish,
-// and closes the listener.
+// Close interrupts all running accept goroutines, waits for them and the connection
+// goroutines to finish, and closes the listener.
 func (s *discardTCPServer) Close() {
 	s.ln.SetDeadline(aLongTimeAgo)
 	s.wg.Wait()
@@ -628,6 +633,38 @@ func TestAddrFunctions(t *testing.T) {
 	}
 }
 
+// TestTCPAddrPortFunctions ensures that [ListenConfig.ListenTCPAddrPort] and
+// [Dialer.DialTCPAddrPort] respect the configuration and return usable typed values.
+func TestTCPAddrPortFunctions(t *testing.T) {
+	for _, c := range cases {
+		c.Run(t, testTCPAddrPortFunctions)
+	}
+}
+
+// TestDialContextBuffers ensures that data in SYN passed as multiple buffers
+// arrives in order with [Dialer.DialContextBuffers].
+func TestDialContextBuffers(t *testing.T) {
+	for _, c := range cases {
+		c.Run(t, testDialContextBuffers)
+	}
+}
+
+// TestDialContextManyBuffers ensures that data in SYN passed as more buffers
+// than a single system call takes arrives in full with [Dialer.DialContextBuffers].
+func TestDialContextManyBuffers(t *testing.T) {
+	for _, c := range cases {
+		c.Run(t, testDialContextManyBuffers)
+	}
+}
+
+// TestDialAddrPorts ensures that [Dialer.DialAddrPorts] skips unusable addresses,
+// moves on from addresses that refuse the connection, and does not use the resolver.
+func TestDialAddrPorts(t *testing.T) {
+	for _, c := range cases {
+		c.Run(t, testDialAddrPorts)
+	}
+}
+
 // TestClientWriteReadServerReadWrite ensures that a client can write to a server,
 // the server can read from the client, and the server can write to the client.
 func TestClientWriteReadServerReadWrite(t *testing.T) {
@@ -660,6 +697,183 @@ func TestSetDeadline(t *testing.T) {
 	}
 }
 
+// TestDialContextInfo ensures that [Dialer.DialContextInfo] reports a dial method
+// consistent with the dialer's configuration.
+func TestDialContextInfo(t *testing.T) {
+	s, err := newDiscardTCPServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(t)
+	defer s.Close()
+
+	address := s.Addr().String()
+
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+			testDialContextInfo(t, c.dialer, address)
+		})
+	}
+}
+
+func testDialContextInfo(t *testing.T, d Dialer, address string) {
+	wantTFO := d.TFO()
+
+	c, info, err := d.DialContextInfo(context.Background(), "tcp", address, hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Wait for the server to drain the connection and close its side,
+	// so that it is done logging before the test returns.
+	c.(*net.TCPConn).CloseWrite()
+	readUntilEOF(c, nil, t)
+
+	t.Logf("info: %+v", info)
+
+	switch info.Method {
+	case DialMethodNoTFO:
+		if !d.DisableTFO {
+			t.Errorf("info.Method = %v, want a TFO method", info.Method)
+		}
+	case DialMethodFallback:
+		if wantTFO && !d.Fallback {
+			t.Errorf("info.Method = %v without Fallback", info.Method)
+		}
+	case DialMethodFastOpenConnect, DialMethodSendmsg:
+		if !wantTFO {
+			t.Errorf("info.Method = %v, but TFO() returned false", info.Method)
+		}
+	default:
+		t.Errorf("unexpected info.Method: %v", info.Method)
+	}
+
+	if info.SYNDataLen < 0 || info.SYNDataLen > len(hello) {
+		t.Errorf("info.SYNDataLen = %d, want [0, %d]", info.SYNDataLen, len(hello))
+	}
+
+	if info.Method != DialMethodFastOpenConnect && info.Method != DialMethodSendmsg && info.SYNDataLen != 0 {
+		t.Errorf("info.SYNDataLen = %d for %v", info.SYNDataLen, info.Method)
+	}
+}
+
+// TestInfoListener ensures that [InfoListener.AcceptTCPInfo] reports
+// a consistent [AcceptInfo] for accepted connections.
+func TestInfoListener(t *testing.T) {
+	for _, c := range cases {
+		c.Run(t, testInfoListener)
+	}
+}
+
+func testInfoListener(t *testing.T, lc ListenConfig, d Dialer) {
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iln := NewInfoListener(ln.(*net.TCPListener))
+	defer iln.Close()
+
+	acceptCh := make(chan struct{})
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+
+		conn, info, err := iln.AcceptTCPInfo()
+		close(acceptCh)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		t.Logf("Accepted %s, info: %+v", conn.RemoteAddr(), info)
+
+		if !info.TFO && info.SYNDataLen != 0 {
+			t.Errorf("info.SYNDataLen = %d without TFO", info.SYNDataLen)
+		}
+		if info.SYNDataLen > len(hello) {
+			t.Errorf("info.SYNDataLen = %d, want at most %d", info.SYNDataLen, len(hello))
+		}
+		readUntilEOF(conn, hello, t)
+	}()
+
+	c, err := d.Dial("tcp", ln.Addr().String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := c.(*net.TCPConn)
+	defer tc.Close()
+
+	// Only send FIN after accept, so that it does not count towards SYNDataLen.
+	<-acceptCh
+	tc.CloseWrite()
+	<-ctrlCh
+}
+
+// TestNoCookie ensures that [Dialer.NoCookie] and [ListenConfig.NoCookie]
+// make the client send data in SYN without a cookie on Linux,
+// and follow the Fallback semantics on other platforms.
+func TestNoCookie(t *testing.T) {
+	if comptimeListenNoTFO || comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	for _, fallback := range []bool{false, true} {
+		t.Run("Fallback="+strconv.FormatBool(fallback), func(t *testing.T) {
+			testNoCookie(t, fallback)
+		})
+	}
+}
+
+func testNoCookie(t *testing.T, fallback bool) {
+	lc := ListenConfig{NoCookie: true, Fallback: fallback}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if runtime.GOOS != "linux" && !fallback {
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("lc.Listen() returned error %v, want ErrUnsupported", err)
+		}
+		if ln != nil {
+			ln.Close()
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, hello, t)
+	}()
+
+	d := Dialer{NoCookie: true, Fallback: fallback}
+	c, info, err := d.DialContextInfo(context.Background(), "tcp", ln.Addr().String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.(*net.TCPConn).CloseWrite()
+	<-ctrlCh
+
+	t.Logf("info: %+v", info)
+
+	if runtime.GOOS == "linux" && info.Method == DialMethodFastOpenConnect {
+		if flags, err := ReadSysctl(""); err == nil && flags.Has(SysctlClientEnable) && info.SYNDataLen != len(hello) {
+			t.Errorf("info.SYNDataLen = %d, want %d", info.SYNDataLen, len(hello))
+		}
+	}
+}
+
 func testRawConnControl(t *testing.T, sc syscall.Conn) {
 	rawConn, err := sc.SyscallConn()
 	if err != nil {
@@ -807,6 +1021,167 @@ func testAddrFunctions(t *testing.T, lc ListenConfig, d Dialer) {
 	}
 }
 
+func testTCPAddrPortFunctions(t *testing.T, lc ListenConfig, d Dialer) {
+	var listenCtrlCalled, dialCtrlCalled bool
+	ctrlFn := lc.Control
+	lc.Control = func(network, address string, c syscall.RawConn) error {
+		listenCtrlCalled = true
+		if ctrlFn != nil {
+			return ctrlFn(network, address, c)
+		}
+		return nil
+	}
+	d.ControlContext = func(_ context.Context, _, _ string, _ syscall.RawConn) error {
+		dialCtrlCalled = true
+		return nil
+	}
+
+	lntcp, err := lc.ListenTCPAddrPort(context.Background(), "tcp", netip.AddrPortFrom(netip.IPv6Loopback(), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lntcp.Close()
+	if !listenCtrlCalled {
+		t.Error("ListenConfig.Control was not called")
+	}
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := lntcp.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, helloworld, t)
+	}()
+
+	raddr := lntcp.Addr().(*net.TCPAddr).AddrPort()
+	tc, err := d.DialTCPAddrPort(context.Background(), "tcp", netip.AddrPort{}, raddr, hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tc.Close()
+	if !dialCtrlCalled {
+		t.Error("Dialer.ControlContext was not called")
+	}
+	if got := tc.RemoteAddr().(*net.TCPAddr).AddrPort(); got != raddr {
+		t.Errorf("tc.RemoteAddr() = %v, want %v", got, raddr)
+	}
+
+	write(tc, world, t)
+	tc.CloseWrite()
+	<-ctrlCh
+
+	if _, err = d.DialTCPAddrPort(context.Background(), "udp", netip.AddrPort{}, raddr, hello); err == nil {
+		t.Error("DialTCPAddrPort with udp network succeeded")
+	}
+	if _, err = d.DialTCPAddrPort(context.Background(), "tcp", netip.AddrPort{}, netip.AddrPort{}, hello); !errors.Is(err, errMissingAddress) {
+		t.Errorf("DialTCPAddrPort with invalid raddr: got %v, want %v", err, errMissingAddress)
+	}
+}
+
+func testDialContextBuffers(t *testing.T, lc ListenConfig, d Dialer) {
+	ln, err := lc.ListenTCP(context.Background(), "tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		readUntilEOF(conn, helloWorldSentence, t)
+	}()
+
+	bufs := net.Buffers{helloWorldSentence[:2], nil, helloWorldSentence[2:7], helloWorldSentence[7:]}
+	c, err := d.DialContextBuffers(context.Background(), "tcp", ln.Addr().String(), bufs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.(*net.TCPConn).CloseWrite()
+	<-ctrlCh
+
+	if len(bufs) != 4 || len(bufs[0]) != 2 || len(bufs[2]) != 5 {
+		t.Errorf("buffers were modified: %q", bufs)
+	}
+}
+
+func testDialAddrPorts(t *testing.T, lc ListenConfig, d Dialer) {
+	d.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			t.Error("Dialer.Resolver was used")
+			return nil, errors.New("unexpected lookup")
+		},
+	}
+
+	closedLn, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closedLn.Addr().(*net.TCPAddr).AddrPort()
+	closedLn.Close()
+
+	lntcp, err := lc.ListenTCPAddrPort(context.Background(), "tcp", netip.AddrPortFrom(netip.IPv6Loopback(), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lntcp.Close()
+	raddr := lntcp.Addr().(*net.TCPAddr).AddrPort()
+
+	for _, b := range [][]byte{hello, nil} {
+		ctrlCh := make(chan struct{})
+		go func() {
+			defer close(ctrlCh)
+			conn, err := lntcp.AcceptTCP()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			readUntilEOF(conn, append(b[:len(b):len(b)], world...), t)
+		}()
+
+		tc, err := d.DialAddrPorts(context.Background(), "tcp", []netip.AddrPort{{}, closedAddr, raddr}, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tc.RemoteAddr().(*net.TCPAddr).AddrPort(); got != raddr {
+			t.Errorf("tc.RemoteAddr() = %v, want %v", got, raddr)
+		}
+		write(tc, world, t)
+		tc.CloseWrite()
+		<-ctrlCh
+		tc.Close()
+	}
+
+	if _, err := d.DialAddrPorts(context.Background(), "tcp6", []netip.AddrPort{closedAddr}, hello); err == nil {
+		t.Error("DialAddrPorts() with no matching addresses succeeded")
+	}
+}
+
+func TestConsumeBuffers(t *testing.T) {
+	bufs := [][]byte{[]byte("hel"), nil, []byte("lo"), []byte("world")}
+	for n := 0; n <= buffersLen(bufs); n++ {
+		rest := consumeBuffers(bufs, n)
+		if got, want := string(flattenBuffers(rest)), "helloworld"[n:]; got != want {
+			t.Errorf("consumeBuffers(bufs, %d) = %q, want %q", n, got, want)
+		}
+	}
+	if got := string(bytes.Join(bufs, nil)); got != "helloworld" {
+		t.Errorf("bufs modified: %q", got)
+	}
+}
+
 func write(w io.Writer, data []byte, t *testing.T) {
 	t.Helper()
 	dataLen := len(data)
@@ -849,6 +1224,16 @@ func readExactlyOneByte(r io.Reader, expectedByte byte, t *testing.T) {
 	}
 }
 
+// noCookieLoopbackHost returns a loopback address the kernel is unlikely to have
+// a TFO cookie for, so that connecting to a closed port fails before connect(2) returns.
+// On Linux, the whole 127.0.0.0/8 is routed to loopback, so a random one is picked.
+func noCookieLoopbackHost() string {
+	if runtime.GOOS != "linux" {
+		return "127.0.0.1"
+	}
+	return net.IPv4(127, byte(rand.Intn(256)), byte(rand.Intn(256)), byte(1+rand.Intn(254))).String()
+}
+
 func readUntilEOF(r io.Reader, expectedData []byte, t *testing.T) {
 	t.Helper()
 	b, err := io.ReadAll(r)
@@ -1069,3 +1454,48 @@ func testSetDeadline(t *testing.T, lc ListenConfig, d Dialer) {
 	tc.CloseWrite()
 	<-ctrlCh
 }
+
+func testDialContextManyBuffers(t *testing.T, lc ListenConfig, d Dialer) {
+	ln, err := lc.ListenTCP(context.Background(), "tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bufs := make(net.Buffers, 3000)
+	for i := range bufs {
+		bufs[i] = helloworld
+	}
+	data := bytes.Join(bufs, nil)
+	zeroCopies := []bool{false, true}
+
+	ctrlCh := make(chan struct{})
+	go func() {
+		defer close(ctrlCh)
+		for range zeroCopies {
+			conn, err := ln.AcceptTCP()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					t.Error(err)
+				}
+				return
+			}
+			readUntilEOF(conn, data, t)
+			conn.Close()
+		}
+	}()
+	defer func() {
+		ln.Close()
+		<-ctrlCh
+	}()
+
+	for _, zeroCopy := range zeroCopies {
+		d.ZeroCopy = zeroCopy
+		c, err := d.DialContextBuffers(context.Background(), "tcp", ln.Addr().String(), bufs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.(*net.TCPConn).CloseWrite()
+		c.Close()
+	}
+	<-ctrlCh
+}
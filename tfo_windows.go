@@ -30,7 +30,7 @@ func setUpdateConnectContext(fd windows.Handle) error {
 	return windows.Setsockopt(fd, windows.SOL_SOCKET, windows.SO_UPDATE_CONNECT_CONTEXT, nil, 0)
 }
 
-func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *net.TCPAddr, b []byte, ctrlCtxFn func(context.Context, string, string, syscall.RawConn) error) (*net.TCPConn, error) {
+func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *net.TCPAddr, bufs [][]byte, synDataLimit int, ctrlCtxFn func(context.Context, string, string, syscall.RawConn) error, info *DialInfo) (*net.TCPConn, error) {
 	ltsa := (*tcpSockaddr)(laddr)
 	rtsa := (*tcpSockaddr)(raddr)
 	family, ipv6only := favoriteAddrFamily(network, ltsa, rtsa, "dial")
@@ -57,7 +57,15 @@ func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *n
 		return nil, err
 	}
 
+	// Like [net.Dialer], do not start connecting if ctx is already done.
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	trace := ContextDialTrace(ctx)
+
 	handle, err := windows.WSASocket(int32(family), windows.SOCK_STREAM, windows.IPPROTO_TCP, nil, 0, windows.WSA_FLAG_OVERLAPPED|windows.WSA_FLAG_NO_HANDLE_INHERIT)
+	trace.socketCreated(network, raddr.String(), err)
 	if err != nil {
 		return nil, os.NewSyscallError("WSASocket", err)
 	}
@@ -73,17 +81,35 @@ func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *n
 		return nil, wrapSyscallError("setsockopt(IPV6_V6ONLY)", err)
 	}
 
-	if err = setNoDelay(handle, 1); err != nil {
+	if err = d.SocketOptions.apply(uintptr(handle), family == windows.AF_INET6); err != nil {
 		fd.Close()
-		return nil, wrapSyscallError("setsockopt(TCP_NODELAY)", err)
+		return nil, err
+	}
+
+	if !d.SocketOptions.DisableNoDelay {
+		if err = setNoDelay(handle, 1); err != nil {
+			fd.Close()
+			return nil, wrapSyscallError("setsockopt(TCP_NODELAY)", err)
+		}
 	}
 
-	if err = setTFODialer(uintptr(handle)); err != nil {
+	method := DialMethodSendmsg
+	err = setTFODialer(uintptr(handle))
+	trace.tfoSockopt(network, raddr.String(), err)
+	if err != nil {
+		tfoErr := newTFOError(TFOStageSockopt, "TCP_FASTOPEN", wrapSyscallError("setsockopt(TCP_FASTOPEN)", err))
 		if !d.Fallback || !errors.Is(err, ErrUnsupported) {
 			fd.Close()
-			return nil, wrapSyscallError("setsockopt(TCP_FASTOPEN)", err)
+			return nil, tfoErr
+		}
+		if runtimeDialTFOSupport.storeNone() {
+			d.Stats.runtimeDisable()
 		}
-		runtimeDialTFOSupport.storeNone()
+		dialFallback(ctx, info, network, raddr.String(), FallbackReasonUnsupported, tfoErr)
+		method = DialMethodFallback
+	} else if err = d.setTFONoCookie(uintptr(handle)); err != nil {
+		fd.Close()
+		return nil, err
 	}
 
 	if ctrlCtxFn != nil {
@@ -104,10 +130,26 @@ func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *n
 		return nil, err
 	}
 
-	if err = connWriteFunc(ctx, fd, func(fd *netFD) error {
-		n, err := fd.pfd.ConnectEx(rsa, b)
+	// ConnectEx takes a single buffer.
+	b := flattenBuffers(bufs)
+	synDataLen := buffersLen(d.synData(bufs, synDataLimit, family == syscall.AF_INET6))
+
+	// ConnectEx completes when the handshake does, so the whole call is the wait
+	// for the SYN-ACK when data goes out with the SYN. A plain handshake and the write
+	// of the rest of the data are not subject to [Dialer.SYNDataTimeout].
+	connectCtx := ctx
+	if method == DialMethodSendmsg && synDataLen > 0 {
+		var cancel context.CancelFunc
+		connectCtx, cancel = d.synDataCtx(ctx)
+		defer cancel()
+	}
+
+	var n int
+
+	if err = connWriteFunc(connectCtx, fd, func(fd *netFD) (err error) {
+		n, err = fd.pfd.ConnectEx(rsa, b[:synDataLen])
 		if err != nil {
-			return os.NewSyscallError("connectex", err)
+			return wrapSyscallError("connectex", err)
 		}
 
 		if err = setUpdateConnectContext(handle); err != nil {
@@ -125,17 +167,42 @@ func (d *Dialer) dialSingle(ctx context.Context, network string, laddr, raddr *n
 			return wrapSyscallError("getpeername", err)
 		}
 		fd.raddr = sockaddrToTCP(rsa)
-
-		if n < len(b) {
-			if _, err = fd.Write(b[n:]); err != nil {
-				return err
-			}
-		}
-
 		return nil
 	}); err != nil {
 		fd.Close()
-		return nil, err
+		if method == DialMethodFallback {
+			return nil, err
+		}
+		tfoErr := newTFOError(TFOStageConnect, "ConnectEx", err)
+		if synDataTimedOut(ctx, connectCtx) {
+			d.markTFOBlackhole(raddr.AddrPort())
+			dialFallback(ctx, info, network, raddr.String(), FallbackReasonBlackhole, tfoErr)
+			return d.dialAndWriteTCPConn(ctx, network, raddr.String(), bufs, info)
+		}
+		// Only errors from ConnectEx itself are TFO errors, not cancellation or deadlines.
+		if !isSyscallError(err, "connectex") {
+			return nil, err
+		}
+		return nil, tfoErr
+	}
+	if connectCtx != ctx {
+		d.markTFOSYNDataAcked()
+	}
+
+	if n < len(b) {
+		if err = connWriteFunc(ctx, fd, func(fd *netFD) error {
+			_, err := fd.Write(b[n:])
+			return err
+		}); err != nil {
+			fd.Close()
+			return nil, err
+		}
+	}
+
+	info.Method = method
+	if method == DialMethodSendmsg {
+		info.SYNDataLen = n
+		trace.synData(network, raddr.String(), n)
 	}
 
 	runtime.SetFinalizer(fd, netFDClose)
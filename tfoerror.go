@@ -0,0 +1,123 @@
+package tfo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// TFOStage is the stage of a TFO dial call at which an error occurred.
+type TFOStage uint8
+
+const (
+	// TFOStageSockopt is setting TFO-related socket options.
+	TFOStageSockopt TFOStage = iota + 1
+
+	// TFOStageConnect is sending the SYN with data and waiting for the handshake.
+	TFOStageConnect
+
+	// TFOStageWrite is writing the data after connect(2) with TCP_FASTOPEN_CONNECT on Linux.
+	// As connect(2) is deferred until the first write, errors from the handshake,
+	// e.g. a refused connection, are reported at this stage.
+	TFOStageWrite
+)
+
+// String returns the string representation of the stage.
+func (s TFOStage) String() string {
+	switch s {
+	case TFOStageSockopt:
+		return "sockopt"
+	case TFOStageConnect:
+		return "connect"
+	case TFOStageWrite:
+		return "write"
+	default:
+		return "TFOStage(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// TFOError records an error that occurred while dialing with TFO.
+//
+// When [Dialer.Fallback] is false, it is returned by dial calls, usually wrapped
+// in a [*net.OpError]. Use [errors.As] to retrieve it. When the dial call falls back
+// to not using TFO because of an error, the error is reported in [DialInfo.FallbackError].
+//
+// For example, TFO being disabled by the kernel is reported at [TFOStageSockopt],
+// or at [TFOStageConnect] with [syscall.EOPNOTSUPP] on Linux, while a peer refusing
+// the connection is reported at [TFOStageConnect] or [TFOStageWrite] with
+// [syscall.ECONNREFUSED].
+type TFOError struct {
+	// Stage is the stage at which the error occurred.
+	Stage TFOStage
+
+	// Mechanism is the platform mechanism that failed,
+	// e.g. "TCP_FASTOPEN_CONNECT", "sendmsg", "connectx" or "ConnectEx".
+	Mechanism string
+
+	// Errno is the errno of the underlying error, or 0 if it has none.
+	Errno syscall.Errno
+
+	// Fallback reports whether the dial call proceeded without TFO.
+	Fallback bool
+
+	// Err is the underlying error.
+	Err error
+}
+
+func newTFOError(stage TFOStage, mechanism string, err error) *TFOError {
+	e := &TFOError{Stage: stage, Mechanism: mechanism, Err: err}
+	errors.As(err, &e.Errno)
+	return e
+}
+
+// wrapTFOError wraps err in a [*TFOError], unless it already contains one.
+func wrapTFOError(stage TFOStage, mechanism string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var tfoErr *TFOError
+	if errors.As(err, &tfoErr) {
+		return err
+	}
+	return newTFOError(stage, mechanism, err)
+}
+
+// isSyscallError returns whether err is an errno returned by the system call with the given name,
+// as opposed to e.g. a cancellation or an expired deadline that interrupted the call.
+func isSyscallError(err error, name string) bool {
+	var (
+		sysErr *os.SyscallError
+		errno  syscall.Errno
+	)
+	return errors.As(err, &sysErr) && sysErr.Syscall == name && errors.As(sysErr.Err, &errno)
+}
+
+func (e *TFOError) Error() string {
+	s := "tfo " + e.Stage.String() + " (" + e.Mechanism + ")"
+	if e.Fallback {
+		s += " fell back"
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *TFOError) Unwrap() error {
+	return e.Err
+}
+
+// dialFallback reports a fallback decision to the trace hooks in ctx and records it in info.
+// err is the error that caused the fallback, if any.
+func dialFallback(ctx context.Context, info *DialInfo, network, addr string, reason FallbackReason, err *TFOError) {
+	info.FallbackReason = reason
+	info.FallbackError = err
+	if err == nil {
+		ContextDialTrace(ctx).fallback(network, addr, reason, nil)
+		return
+	}
+	err.Fallback = true
+	ContextDialTrace(ctx).fallback(network, addr, reason, err)
+}
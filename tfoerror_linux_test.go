@@ -0,0 +1,36 @@
+package tfo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestDialDeadlineNonTFOError ensures that a dial call interrupted by its deadline
+// while waiting for the handshake does not report a [*TFOError].
+func TestDialDeadlineNonTFOError(t *testing.T) {
+	runtimeFallbackSetDialLinuxSendto(t)
+	ln, _ := listenFullBacklog(t)
+
+	// The health cache makes the dial call wait for the SYN-ACK, which never comes.
+	d := Dialer{Fallback: true, NoCookie: true, HealthCache: new(HealthCache)}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	c, err := d.DialContext(ctx, "tcp", ln.Addr().String(), hello)
+	if err == nil {
+		c.Close()
+		t.Fatal("dial succeeded, want error")
+	}
+	t.Logf("err: %v", err)
+
+	var tfoErr *TFOError
+	if errors.As(err, &tfoErr) {
+		t.Errorf("errors.As found *TFOError in %v", err)
+	}
+	if !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want a deadline error", err)
+	}
+}
@@ -0,0 +1,180 @@
+package tfo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestTFOError(t *testing.T) {
+	serr := os.NewSyscallError("setsockopt(TCP_FASTOPEN_CONNECT)", syscall.ENOPROTOOPT)
+	var err error = &net.OpError{Op: "dial", Net: "tcp", Err: newTFOError(TFOStageSockopt, "TCP_FASTOPEN_CONNECT", serr)}
+
+	var tfoErr *TFOError
+	if !errors.As(err, &tfoErr) {
+		t.Fatal("errors.As failed to find *TFOError")
+	}
+	if tfoErr.Stage != TFOStageSockopt || tfoErr.Errno != syscall.ENOPROTOOPT || tfoErr.Fallback {
+		t.Errorf("unexpected TFOError: %+v", tfoErr)
+	}
+	if !errors.Is(err, syscall.ENOPROTOOPT) {
+		t.Error("errors.Is failed to find the errno")
+	}
+	if got, want := tfoErr.Error(), "tfo sockopt (TCP_FASTOPEN_CONNECT): "+serr.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	if wrapped := wrapTFOError(TFOStageConnect, "sendmsg", err); wrapped != err {
+		t.Errorf("wrapTFOError wrapped an error that already contains a *TFOError: %v", wrapped)
+	}
+}
+
+// TestDialTFOError ensures that a failed TFO dial call returns a [*TFOError].
+func TestDialTFOError(t *testing.T) {
+	if comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	// If the kernel has a TFO cookie for the address, connect(2) returns
+	// before the connection is refused.
+	ln, err := net.Listen("tcp", net.JoinHostPort(noCookieLoopbackHost(), "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+	}{
+		{"Default", runtimeFallbackAsIs},
+		{"LinuxSendto", runtimeFallbackSetDialLinuxSendto},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+
+			d := Dialer{Fallback: true}
+			c, info, err := d.DialContextInfo(context.Background(), "tcp", address, hello)
+			if err == nil {
+				c.Close()
+				if info.SYNDataLen > 0 {
+					t.Skip("the kernel has a TFO cookie for", address)
+				}
+				t.Fatal("Dial to a closed port succeeded")
+			}
+			t.Logf("err: %v", err)
+
+			var tfoErr *TFOError
+			if !errors.As(err, &tfoErr) {
+				t.Fatalf("errors.As failed to find *TFOError in %v", err)
+			}
+			if tfoErr.Stage != TFOStageConnect && tfoErr.Stage != TFOStageWrite {
+				t.Errorf("tfoErr.Stage = %v, want connect or write", tfoErr.Stage)
+			}
+			if tfoErr.Errno == 0 {
+				t.Error("tfoErr.Errno is 0")
+			}
+			if tfoErr.Fallback {
+				t.Error("tfoErr.Fallback is true for a returned error")
+			}
+		})
+	}
+}
+
+// TestDialNonTFOError ensures that dial calls do not report errors unrelated to TFO
+// as a [*TFOError].
+func TestDialNonTFOError(t *testing.T) {
+	if comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	errControl := errors.New("control error")
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, c := range []struct {
+		name    string
+		ctx     context.Context
+		dialer  Dialer
+		address string
+	}{
+		{
+			name: "Control",
+			ctx:  context.Background(),
+			dialer: Dialer{Dialer: net.Dialer{Control: func(_, _ string, _ syscall.RawConn) error {
+				return errControl
+			}}},
+			address: "127.0.0.1:1",
+		},
+		{
+			name:    "Canceled",
+			ctx:     canceledCtx,
+			address: "127.0.0.1:1",
+		},
+		{
+			name:    "Lookup",
+			ctx:     context.Background(),
+			address: "tfo.invalid:1",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			// Happy Eyeballs makes dial calls on Linux use sendto(MSG_FASTOPEN).
+			for _, he := range []*HappyEyeballs{nil, {}} {
+				d := c.dialer
+				d.HappyEyeballs = he
+				conn, err := d.DialContext(c.ctx, "tcp", c.address, hello)
+				if err == nil {
+					conn.Close()
+					t.Fatal("dial succeeded, want error")
+				}
+				var tfoErr *TFOError
+				if errors.As(err, &tfoErr) {
+					t.Errorf("errors.As found *TFOError in %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestDialInfoFallbackReason ensures that [DialInfo] reports why TFO was not used.
+func TestDialInfoFallbackReason(t *testing.T) {
+	if comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	s, err := newDiscardTCPServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(t)
+	defer s.Close()
+
+	var h HealthCache
+	h.MarkFailed(s.Addr().AddrPort())
+
+	d := Dialer{Fallback: true, HealthCache: &h}
+	c, info, err := d.DialContextInfo(context.Background(), "tcp", s.Addr().String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	if info.Method != DialMethodFallback || info.FallbackReason != FallbackReasonUnhealthy || info.FallbackError != nil {
+		t.Errorf("info = %+v, want fallback for unhealthy destination without error", info)
+	}
+
+	h.Reset()
+	c, info, err = d.DialContextInfo(context.Background(), "tcp", s.Addr().String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	if info.Method != DialMethodFallback && (info.FallbackReason != 0 || info.FallbackError != nil) {
+		t.Errorf("info = %+v, want no fallback reason for %v", info, info.Method)
+	}
+}
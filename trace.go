@@ -0,0 +1,250 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// FallbackReason is the reason a dial call proceeded without TFO.
+type FallbackReason uint8
+
+const (
+	// FallbackReasonUnsupported means the system rejected the attempt to use TFO.
+	FallbackReasonUnsupported FallbackReason = iota + 1
+
+	// FallbackReasonDisabled means TFO was disabled for the whole process,
+	// after an earlier dial call found it unsupported or detected a blackhole.
+	FallbackReasonDisabled
+
+	// FallbackReasonUnhealthy means [Dialer.HealthCache] has a failure recorded for the destination.
+	FallbackReasonUnhealthy
+
+	// FallbackReasonBlackhole means the SYN carrying data went unanswered
+	// for [Dialer.SYNDataTimeout].
+	FallbackReasonBlackhole
+)
+
+// String returns the string representation of the fallback reason.
+func (r FallbackReason) String() string {
+	switch r {
+	case FallbackReasonUnsupported:
+		return "unsupported"
+	case FallbackReasonDisabled:
+		return "disabled"
+	case FallbackReasonUnhealthy:
+		return "unhealthy destination"
+	case FallbackReasonBlackhole:
+		return "blackhole"
+	default:
+		return "FallbackReason(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// DialTrace is a set of hooks to run at various stages of a TFO dial call.
+// Any particular hook may be nil. Hooks may be called concurrently from different goroutines,
+// e.g. when racing IPv4 and IPv6 addresses.
+//
+// ConnectDone is called once for each call to ConnectStart.
+//
+// When TCP_FASTOPEN_CONNECT is used on Linux, name resolution and address racing are done
+// by [net.Dialer], so DNSStart, DNSDone and RacerStart are not called, and attempts
+// that lose the race are reported to ConnectDone with [context.Canceled].
+//
+// When [Dialer.HappyEyeballs] is set, RacerStart is not called. With a positive
+// [HappyEyeballs.ResolutionDelay], DNSStart and DNSDone are called once for each address family.
+type DialTrace struct {
+	// DNSStart is called when a DNS lookup begins.
+	DNSStart func(host string)
+
+	// DNSDone is called when a DNS lookup ends.
+	DNSDone func(addrs []net.IPAddr, err error)
+
+	// RacerStart is called when one of the racers of a dual-stack dial call starts
+	// dialing its addresses.
+	RacerStart func(primary bool, addrs []*net.TCPAddr)
+
+	// ConnectStart is called when a connection attempt to an address begins.
+	ConnectStart func(network, addr string)
+
+	// SocketCreated is called after the socket for a connection attempt is created.
+	SocketCreated func(network, addr string, err error)
+
+	// TFOSockopt is called with the result of enabling TFO on the socket.
+	TFOSockopt func(network, addr string, err error)
+
+	// Fallback is called when the dial call proceeds without TFO.
+	// err is the error that caused the fallback, if any, as a [*TFOError].
+	Fallback func(network, addr string, reason FallbackReason, err error)
+
+	// SYNData is called with the number of bytes handed to the kernel
+	// along with the connection request.
+	SYNData func(network, addr string, n int)
+
+	// ConnectDone is called when a connection attempt to an address completes.
+	ConnectDone func(network, addr string, err error)
+}
+
+type dialTraceContextKey struct{}
+
+// WithDialTrace returns a new context based on the provided parent ctx.
+// TFO dial calls made with the returned context use the provided trace hooks,
+// in addition to any previous hooks registered with ctx or [Dialer.Trace].
+// The provided trace's hooks are called first.
+func WithDialTrace(ctx context.Context, trace *DialTrace) context.Context {
+	if trace == nil {
+		panic("nil trace")
+	}
+	return context.WithValue(ctx, dialTraceContextKey{}, trace.compose(ContextDialTrace(ctx)))
+}
+
+// ContextDialTrace returns the [DialTrace] associated with the provided context.
+// If none, it returns nil.
+func ContextDialTrace(ctx context.Context) *DialTrace {
+	trace, _ := ctx.Value(dialTraceContextKey{}).(*DialTrace)
+	return trace
+}
+
+// withTrace attaches [Dialer.Trace], and the hooks of [Dialer.Stats], to ctx.
+func (d *Dialer) withTrace(ctx context.Context) context.Context {
+	if d.Trace != nil {
+		ctx = WithDialTrace(ctx, d.Trace)
+	}
+	if d.Stats != nil {
+		ctx = WithDialTrace(ctx, d.Stats.dialTrace())
+	}
+	return ctx
+}
+
+// compose returns a trace that calls the hooks of t followed by those of old.
+func (t *DialTrace) compose(old *DialTrace) *DialTrace {
+	if old == nil {
+		return t
+	}
+	nt := *t
+	if hook, oldHook := t.DNSStart, old.DNSStart; hook == nil {
+		nt.DNSStart = oldHook
+	} else if oldHook != nil {
+		nt.DNSStart = func(host string) {
+			hook(host)
+			oldHook(host)
+		}
+	}
+	if hook, oldHook := t.DNSDone, old.DNSDone; hook == nil {
+		nt.DNSDone = oldHook
+	} else if oldHook != nil {
+		nt.DNSDone = func(addrs []net.IPAddr, err error) {
+			hook(addrs, err)
+			oldHook(addrs, err)
+		}
+	}
+	if hook, oldHook := t.RacerStart, old.RacerStart; hook == nil {
+		nt.RacerStart = oldHook
+	} else if oldHook != nil {
+		nt.RacerStart = func(primary bool, addrs []*net.TCPAddr) {
+			hook(primary, addrs)
+			oldHook(primary, addrs)
+		}
+	}
+	nt.ConnectStart = composeAddrHook(t.ConnectStart, old.ConnectStart)
+	nt.SocketCreated = composeAddrErrHook(t.SocketCreated, old.SocketCreated)
+	nt.TFOSockopt = composeAddrErrHook(t.TFOSockopt, old.TFOSockopt)
+	if hook, oldHook := t.Fallback, old.Fallback; hook == nil {
+		nt.Fallback = oldHook
+	} else if oldHook != nil {
+		nt.Fallback = func(network, addr string, reason FallbackReason, err error) {
+			hook(network, addr, reason, err)
+			oldHook(network, addr, reason, err)
+		}
+	}
+	if hook, oldHook := t.SYNData, old.SYNData; hook == nil {
+		nt.SYNData = oldHook
+	} else if oldHook != nil {
+		nt.SYNData = func(network, addr string, n int) {
+			hook(network, addr, n)
+			oldHook(network, addr, n)
+		}
+	}
+	nt.ConnectDone = composeAddrErrHook(t.ConnectDone, old.ConnectDone)
+	return &nt
+}
+
+func composeAddrHook(hook, oldHook func(network, addr string)) func(network, addr string) {
+	if hook == nil {
+		return oldHook
+	}
+	if oldHook == nil {
+		return hook
+	}
+	return func(network, addr string) {
+		hook(network, addr)
+		oldHook(network, addr)
+	}
+}
+
+func composeAddrErrHook(hook, oldHook func(network, addr string, err error)) func(network, addr string, err error) {
+	if hook == nil {
+		return oldHook
+	}
+	if oldHook == nil {
+		return hook
+	}
+	return func(network, addr string, err error) {
+		hook(network, addr, err)
+		oldHook(network, addr, err)
+	}
+}
+
+func (t *DialTrace) dnsStart(host string) {
+	if t != nil && t.DNSStart != nil {
+		t.DNSStart(host)
+	}
+}
+
+func (t *DialTrace) dnsDone(addrs []net.IPAddr, err error) {
+	if t != nil && t.DNSDone != nil {
+		t.DNSDone(addrs, err)
+	}
+}
+
+func (t *DialTrace) racerStart(primary bool, addrs []*net.TCPAddr) {
+	if t != nil && t.RacerStart != nil {
+		t.RacerStart(primary, addrs)
+	}
+}
+
+func (t *DialTrace) connectStart(network, addr string) {
+	if t != nil && t.ConnectStart != nil {
+		t.ConnectStart(network, addr)
+	}
+}
+
+func (t *DialTrace) socketCreated(network, addr string, err error) {
+	if t != nil && t.SocketCreated != nil {
+		t.SocketCreated(network, addr, err)
+	}
+}
+
+func (t *DialTrace) tfoSockopt(network, addr string, err error) {
+	if t != nil && t.TFOSockopt != nil {
+		t.TFOSockopt(network, addr, err)
+	}
+}
+
+func (t *DialTrace) fallback(network, addr string, reason FallbackReason, err error) {
+	if t != nil && t.Fallback != nil {
+		t.Fallback(network, addr, reason, err)
+	}
+}
+
+func (t *DialTrace) synData(network, addr string, n int) {
+	if t != nil && t.SYNData != nil {
+		t.SYNData(network, addr, n)
+	}
+}
+
+func (t *DialTrace) connectDone(network, addr string, err error) {
+	if t != nil && t.ConnectDone != nil {
+		t.ConnectDone(network, addr, err)
+	}
+}
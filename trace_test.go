@@ -0,0 +1,201 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type traceRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *traceRecorder) add(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *traceRecorder) has(prefix string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if strings.HasPrefix(e, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *traceRecorder) count(event string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, e := range r.events {
+		if e == event {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *traceRecorder) trace() *DialTrace {
+	return &DialTrace{
+		DNSStart:      func(host string) { r.add("DNSStart") },
+		ConnectStart:  func(network, addr string) { r.add("ConnectStart") },
+		SocketCreated: func(network, addr string, err error) { r.add("SocketCreated") },
+		TFOSockopt:    func(network, addr string, err error) { r.add("TFOSockopt") },
+		Fallback: func(network, addr string, reason FallbackReason, err error) {
+			r.add("Fallback " + reason.String())
+		},
+		SYNData:     func(network, addr string, n int) { r.add("SYNData") },
+		ConnectDone: func(network, addr string, err error) { r.add("ConnectDone") },
+	}
+}
+
+func TestDialTraceCompose(t *testing.T) {
+	var order []string
+	first := &DialTrace{ConnectStart: func(network, addr string) { order = append(order, "first") }}
+	second := &DialTrace{
+		ConnectStart: func(network, addr string) { order = append(order, "second") },
+		DNSStart:     func(host string) { order = append(order, "dns") },
+	}
+
+	ctx := WithDialTrace(context.Background(), second)
+	ctx = WithDialTrace(ctx, first)
+	trace := ContextDialTrace(ctx)
+	trace.connectStart("tcp", "")
+	trace.dnsStart("")
+	trace.connectDone("tcp", "", nil)
+
+	if got, want := strings.Join(order, ","), "first,second,dns"; got != want {
+		t.Errorf("hooks called in order %q, want %q", got, want)
+	}
+
+	var nilTrace *DialTrace
+	nilTrace.connectStart("tcp", "")
+}
+
+// TestDialTrace ensures that the hooks in [Dialer.Trace] are called on dial calls.
+func TestDialTrace(t *testing.T) {
+	if comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	s, err := newDiscardTCPServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(t)
+	defer s.Close()
+
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+	}{
+		{"Default", runtimeFallbackAsIs},
+		{"LinuxSendto", runtimeFallbackSetDialLinuxSendto},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+			testDialTrace(t, s.Addr().AddrPort())
+		})
+	}
+}
+
+func testDialTrace(t *testing.T, ap netip.AddrPort) {
+	var r traceRecorder
+	d := Dialer{Fallback: true, Trace: r.trace()}
+	conn, info, err := d.DialContextInfo(context.Background(), "tcp", ap.String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	t.Logf("events: %v", r.events)
+
+	for _, event := range []string{"ConnectStart", "SocketCreated", "TFOSockopt", "ConnectDone"} {
+		if !r.has(event) {
+			t.Errorf("%s was not called", event)
+		}
+	}
+	if info.Method != DialMethodFallback && !r.has("SYNData") {
+		t.Errorf("SYNData was not called for %v", info.Method)
+	}
+
+	var h HealthCache
+	h.MarkFailed(ap)
+	r = traceRecorder{}
+	d.HealthCache = &h
+	conn, _, err = d.DialContextInfo(context.Background(), "tcp", ap.String(), hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	t.Logf("events: %v", r.events)
+
+	if want := "Fallback " + FallbackReasonUnhealthy.String(); !r.has(want) {
+		t.Errorf("%q was not recorded", want)
+	}
+}
+
+// TestDialTraceConnectBalanced ensures that ConnectDone is called once for each call
+// to ConnectStart, whether the dial call succeeds, falls back, or fails.
+func TestDialTraceConnectBalanced(t *testing.T) {
+	if comptimeDialNoTFO {
+		t.Skip("not applicable to the current platform")
+	}
+
+	s, err := newDiscardTCPServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(t)
+	defer s.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := ln.Addr().String()
+	ln.Close()
+
+	var unhealthy HealthCache
+	unhealthy.MarkFailed(s.Addr().AddrPort())
+
+	for _, c := range []struct {
+		name               string
+		setRuntimeFallback runtimeFallbackHelperFunc
+	}{
+		{"Default", runtimeFallbackAsIs},
+		{"LinuxSendto", runtimeFallbackSetDialLinuxSendto},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.setRuntimeFallback(t)
+			for _, dc := range []struct {
+				name    string
+				dialer  Dialer
+				address string
+			}{
+				{"Success", Dialer{Fallback: true}, s.Addr().String()},
+				{"Unhealthy", Dialer{Fallback: true, HealthCache: &unhealthy}, s.Addr().String()},
+				{"Refused", Dialer{}, refused},
+				{"Lookup", Dialer{}, "tfo.invalid:1"},
+			} {
+				var r traceRecorder
+				d := dc.dialer
+				d.Trace = r.trace()
+				conn, err := d.Dial("tcp", dc.address, hello)
+				if err == nil {
+					conn.Close()
+				}
+				if starts, dones := r.count("ConnectStart"), r.count("ConnectDone"); starts != dones {
+					t.Errorf("%s: ConnectStart called %d times, ConnectDone called %d times: %v", dc.name, starts, dones, r.events)
+				}
+			}
+		})
+	}
+}
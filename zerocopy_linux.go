@@ -0,0 +1,182 @@
+package tfo
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// zeroCopy tracks the MSG_ZEROCOPY sends on a socket, so that the dial call only returns
+// once the kernel no longer references the caller's buffers.
+//
+// Each send call that returns a positive byte count is assigned the next notification ID,
+// starting at 0. Failed send calls give their ID back. The kernel reports completed IDs
+// as ranges on the socket's error queue.
+type zeroCopy struct {
+	sends     uint32
+	completed uint32
+}
+
+// newZeroCopy enables SO_ZEROCOPY on the socket. It returns nil if the kernel does not
+// support it, in which case the data is copied as usual.
+func newZeroCopy(fd uintptr) *zeroCopy {
+	if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ZEROCOPY, 1); err != nil {
+		return nil
+	}
+	return &zeroCopy{}
+}
+
+// synData returns the prefix of synBufs to send in SYN to an IPv4 or IPv6 destination.
+// The kernel copies data in SYN anyway, so it is sent without MSG_ZEROCOPY.
+// If z is not nil, it is capped to the default MSS, so that the rest of the data
+// is sent with MSG_ZEROCOPY after the handshake.
+func (z *zeroCopy) synData(synBufs [][]byte, ipv6 bool) [][]byte {
+	if z == nil || buffersLen(synBufs) <= defaultMSS(ipv6) {
+		return synBufs
+	}
+	return prefixBuffers(synBufs, defaultMSS(ipv6))
+}
+
+// sent records the result of a send call made with MSG_ZEROCOPY.
+func (z *zeroCopy) sent(n int, err error) {
+	if z != nil && err == nil && n > 0 {
+		z.sends++
+	}
+}
+
+// writeBuffers writes bufs to tc, with MSG_ZEROCOPY if z is not nil.
+func (z *zeroCopy) writeBuffers(ctx context.Context, tc *net.TCPConn, bufs [][]byte) error {
+	if z == nil {
+		return netConnWriteBuffers(ctx, tc, bufs)
+	}
+
+	rawConn, err := tc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	return connWriteFunc(ctx, tc, func(tc *net.TCPConn) error {
+		var err error
+		if perr := rawConn.Write(func(fd uintptr) bool {
+			for len(bufs) > 0 {
+				var n int
+				n, err = unix.SendmsgBuffers(int(fd), capBuffers(bufs), nil, nil, unix.MSG_ZEROCOPY|unix.MSG_NOSIGNAL)
+				z.sent(n, err)
+				switch err {
+				case nil:
+					bufs = consumeBuffers(bufs, n)
+				case unix.EAGAIN, unix.EINTR:
+					return false
+				default:
+					return true
+				}
+			}
+			return true
+		}); perr != nil {
+			return perr
+		}
+		return wrapSyscallError("sendmsg", err)
+	})
+}
+
+// abort makes closing tc reset the connection if z is not nil,
+// so that the kernel drops its references to the buffers right away.
+func (z *zeroCopy) abort(tc *net.TCPConn) {
+	if z != nil {
+		_ = tc.SetLinger(0)
+	}
+}
+
+// release waits until the kernel has released the buffers of all send calls.
+// This usually happens when the peer acknowledges the data.
+func (z *zeroCopy) release(ctx context.Context, tc *net.TCPConn) error {
+	if z == nil || z.completed >= z.sends {
+		return nil
+	}
+
+	rawConn, err := tc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	// Notifications are signaled with EPOLLERR alone, which the runtime poller
+	// reports as an error instead of waiting for it, so wait with poll(2) instead,
+	// along with an eventfd that is signaled when ctx is done.
+	efd, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		return wrapSyscallError("eventfd", err)
+	}
+	ef := os.NewFile(uintptr(efd), "")
+	defer ef.Close()
+	stop := AfterFunc(ctx, func() {
+		// Any non-zero value makes the eventfd readable.
+		_, _ = ef.Write([]byte{1, 1, 1, 1, 1, 1, 1, 1})
+	})
+	defer stop()
+
+	if cerr := rawConn.Control(func(fd uintptr) {
+		for z.completed < z.sends {
+			if err = z.reap(fd); err != unix.EAGAIN {
+				if err != nil {
+					err = wrapSyscallError("recvmsg", err)
+					return
+				}
+				continue
+			}
+			fds := []unix.PollFd{{Fd: int32(fd)}, {Fd: int32(efd), Events: unix.POLLIN}}
+			if _, err = unix.Poll(fds, -1); err != nil && err != unix.EINTR {
+				err = wrapSyscallError("poll", err)
+				return
+			}
+			if fds[1].Revents != 0 {
+				err = ctx.Err()
+				return
+			}
+		}
+		err = nil
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// reap reads a notification from the socket's error queue.
+func (z *zeroCopy) reap(fd uintptr) error {
+	var serr unix.SockExtendedErr
+	oob := make([]byte, unix.CmsgSpace(int(unsafe.Sizeof(serr))))
+	_, oobn, _, _, err := unix.Recvmsg(int(fd), nil, oob, unix.MSG_ERRQUEUE)
+	if err != nil {
+		return err
+	}
+
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return os.NewSyscallError("parse socket control message", err)
+	}
+
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == unix.SOL_IP && msg.Header.Type == unix.IP_RECVERR:
+		case msg.Header.Level == unix.SOL_IPV6 && msg.Header.Type == unix.IPV6_RECVERR:
+		default:
+			continue
+		}
+		if len(msg.Data) < int(unsafe.Sizeof(serr)) {
+			continue
+		}
+		serr = *(*unix.SockExtendedErr)(unsafe.Pointer(&msg.Data[0]))
+		if serr.Origin != unix.SO_EE_ORIGIN_ZEROCOPY {
+			if serr.Errno != 0 {
+				return syscall.Errno(serr.Errno)
+			}
+			continue
+		}
+		// [ee_info, ee_data] is the inclusive range of completed IDs.
+		z.completed += serr.Data - serr.Info + 1
+	}
+	return nil
+}
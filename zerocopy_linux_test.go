@@ -0,0 +1,234 @@
+package tfo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestDialZeroCopy ensures that dial calls with [Dialer.ZeroCopy] deliver
+// large payloads intact, with and without data in SYN.
+func TestDialZeroCopy(t *testing.T) {
+	payload := make([]byte, 1<<20)
+	for i := range payload {
+		payload[i] = byte(i * 7)
+	}
+
+	for _, c := range dialerCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.checkSkip(t)
+			c.setRuntimeFallback(t)
+			for _, maxSYNData := range []int{0, 100} {
+				d := c.dialer
+				d.ZeroCopy = true
+				d.MaxSYNData = maxSYNData
+				testDialZeroCopy(t, d, payload)
+			}
+		})
+	}
+}
+
+func testDialZeroCopy(t *testing.T, d Dialer, payload []byte) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			t.Error(err)
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		b, err := io.ReadAll(conn)
+		if err != nil {
+			t.Error(err)
+		}
+		received <- b
+	}()
+
+	c, info, err := d.DialContextInfo(context.Background(), "tcp", ln.Addr().String(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	t.Logf("info: %+v", info)
+	c.(*net.TCPConn).CloseWrite()
+
+	if b := <-received; !bytes.Equal(b, payload) {
+		t.Errorf("received %d bytes, want the %d-byte payload", len(b), len(payload))
+	}
+}
+
+// TestDialZeroCopySYNData ensures that dial calls with [Dialer.ZeroCopy] still send data
+// in SYN, on sockets that are not established yet, and send the rest after the handshake.
+func TestDialZeroCopySYNData(t *testing.T) {
+	lc := ListenConfig{NoCookie: true}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	payload := make([]byte, 1<<20)
+	for i := range payload {
+		payload[i] = byte(i * 7)
+	}
+
+	for _, he := range []*HappyEyeballs{nil, {}} {
+		received := make(chan []byte, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Error(err)
+				received <- nil
+				return
+			}
+			defer conn.Close()
+			b, err := io.ReadAll(conn)
+			if err != nil {
+				t.Error(err)
+			}
+			received <- b
+		}()
+
+		d := Dialer{NoCookie: true, ZeroCopy: true, HappyEyeballs: he}
+		c, info, err := d.DialContextInfo(context.Background(), "tcp", ln.Addr().String(), payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("info: %+v", info)
+		c.(*net.TCPConn).CloseWrite()
+		if b := <-received; !bytes.Equal(b, payload) {
+			t.Errorf("received %d bytes, want the %d-byte payload", len(b), len(payload))
+		}
+		c.Close()
+
+		if flags, err := ReadSysctl(""); err == nil && flags.Has(SysctlClientEnable) {
+			if info.SYNDataLen <= 0 || info.SYNDataLen > defaultIPv6MSS {
+				t.Errorf("info.SYNDataLen = %d with %v, want (0, %d]", info.SYNDataLen, info.Method, defaultIPv6MSS)
+			}
+		}
+	}
+}
+
+// TestZeroCopyRelease ensures that the completion notifications of zero-copy sends are reaped.
+func TestZeroCopyRelease(t *testing.T) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.AcceptTCP()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	tc, err := net.DialTCP("tcp", nil, ln.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tc.Close()
+
+	rawConn, err := tc.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zc *zeroCopy
+	if err = rawConn.Control(func(fd uintptr) {
+		zc = newZeroCopy(fd)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if zc == nil {
+		t.Skip("SO_ZEROCOPY is not supported")
+	}
+
+	payload := make([]byte, 1<<20)
+	if err = zc.writeBuffers(context.Background(), tc, [][]byte{payload[:1000], payload[1000:]}); err != nil {
+		t.Fatal(err)
+	}
+	if zc.sends == 0 {
+		t.Fatal("no zero-copy sends recorded")
+	}
+	if err = zc.release(context.Background(), tc); err != nil {
+		t.Fatal(err)
+	}
+	if zc.completed != zc.sends {
+		t.Errorf("completed = %d, want %d", zc.completed, zc.sends)
+	}
+}
+
+// TestZeroCopyReleaseCanceled ensures that waiting for the completion notifications
+// of zero-copy sends returns once the context is done.
+func TestZeroCopyReleaseCanceled(t *testing.T) {
+	// The small receive buffer keeps most of the data unacknowledged,
+	// as the server never reads.
+	lc := net.ListenConfig{Control: func(_, _ string, c syscall.RawConn) (err error) {
+		if cerr := c.Control(func(fd uintptr) {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF, 4096)
+		}); cerr != nil {
+			return cerr
+		}
+		return err
+	}}
+	ln, err := lc.Listen(context.Background(), "tcp", "[::1]:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	tc, err := net.DialTCP("tcp", nil, ln.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tc.Close()
+	if err = tc.SetWriteBuffer(4 << 20); err != nil {
+		t.Fatal(err)
+	}
+
+	rawConn, err := tc.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zc *zeroCopy
+	if err = rawConn.Control(func(fd uintptr) {
+		zc = newZeroCopy(fd)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if zc == nil {
+		t.Skip("SO_ZEROCOPY is not supported")
+	}
+	defer zc.abort(tc)
+
+	payload := make([]byte, 256<<10)
+	if err = zc.writeBuffers(context.Background(), tc, [][]byte{payload}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err = zc.release(ctx, tc); err != context.DeadlineExceeded {
+		t.Fatalf("release() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("release() returned after %v, want about 100ms", elapsed)
+	}
+}
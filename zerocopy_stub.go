@@ -0,0 +1,28 @@
+//go:build !linux
+
+package tfo
+
+import (
+	"context"
+	"net"
+)
+
+type zeroCopy struct{}
+
+func newZeroCopy(fd uintptr) *zeroCopy {
+	return nil
+}
+
+func (*zeroCopy) synData(synBufs [][]byte, ipv6 bool) [][]byte {
+	return synBufs
+}
+
+func (*zeroCopy) writeBuffers(ctx context.Context, tc *net.TCPConn, bufs [][]byte) error {
+	return netConnWriteBuffers(ctx, tc, bufs)
+}
+
+func (*zeroCopy) abort(tc *net.TCPConn) {}
+
+func (*zeroCopy) release(ctx context.Context, tc *net.TCPConn) error {
+	return nil
+}